@@ -0,0 +1,132 @@
+package ASW_CSV
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+
+	"FCU_Tools/Config"
+)
+
+// Column 은 asw.csv의 논리 열 이름이다. 실제 헤더 이름은 Config.AswColumns 의 별칭으로 찾는다.
+type Column string
+
+const (
+	Component     Column = "Component"
+	Runnable      Column = "Runnable"
+	Port          Column = "Port"
+	PortType      Column = "PortType"
+	InterfaceType Column = "InterfaceType"
+	DeOp          Column = "DE_OP"
+)
+
+// allColumns 는 Load 가 헤더에서 찾아 보는 모든 논리 열이다.
+var allColumns = []Column{Component, Runnable, Port, PortType, InterfaceType, DeOp}
+
+// aliases 는 논리 열마다 설정된 헤더 별칭 목록을 돌려준다.
+func aliases(columns Config.AswColumns, col Column) []string {
+	switch col {
+	case Component:
+		return columns.Component
+	case Runnable:
+		return columns.Runnable
+	case Port:
+		return columns.Port
+	case PortType:
+		return columns.PortType
+	case InterfaceType:
+		return columns.InterfaceType
+	case DeOp:
+		return columns.DeOp
+	}
+	return nil
+}
+
+// Table 은 헤더로 열 위치를 찾은 asw.csv 내용이다.
+// 열 순서가 바뀌거나 열이 추가되어도 헤더 이름(별칭)으로 값을 읽는다.
+type Table struct {
+	Path   string
+	Header []string
+	Rows   [][]string // 헤더를 제외한 데이터 행
+	index  map[Column]int
+}
+
+// Load 는 asw.csv를 읽고 첫 행(헤더)에서 모든 논리 열의 위치를 찾는다.
+// 헤더 비교는 앞뒤 공백과 대소문자를 무시한다. required 에 없는 열은 없어도 되며, 그 값은 빈 문자열로 읽힌다.
+// required 열 중 하나라도 없으면, 없는 열과 그 별칭, 실제로 찾은 헤더를 모두 적어 오류를 돌려준다.
+func Load(path string, columns Config.AswColumns, required ...Column) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("CSV 파일 열기 실패: %v", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	// 각 행마다 컬럼 수가 달라도 읽을 수 있도록 설정
+	r.FieldsPerRecord = -1
+
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("CSV 행 읽기 실패: %v", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("asw.csv가 비어 있습니다 [%s]", path)
+	}
+
+	t := &Table{Path: path, Header: rows[0], Rows: rows[1:], index: make(map[Column]int)}
+	if len(t.Header) > 0 {
+		// Excel이 저장한 UTF-8 BOM 제거
+		t.Header[0] = strings.TrimPrefix(t.Header[0], "\ufeff")
+	}
+
+	for _, col := range allColumns {
+		if idx := findColumn(t.Header, aliases(columns, col)); idx >= 0 {
+			t.index[col] = idx
+		}
+	}
+
+	var missing []string
+	for _, col := range required {
+		if !t.Has(col) {
+			missing = append(missing, fmt.Sprintf("%s (별칭: %s)", col, strings.Join(aliases(columns, col), ", ")))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("asw.csv [%s]에 필요한 열이 없습니다: %s; 찾은 열: %s",
+			path, strings.Join(missing, "; "), strings.Join(t.Header, ", "))
+	}
+	return t, nil
+}
+
+func findColumn(header []string, names []string) int {
+	for _, name := range names {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(name)) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Has 는 헤더에서 col 열을 찾았는지 알려준다.
+func (t *Table) Has(col Column) bool {
+	_, ok := t.index[col]
+	return ok
+}
+
+// RowNumber 는 Rows[i] 의 asw.csv 행 번호이다 (헤더가 1행).
+func (t *Table) RowNumber(i int) int {
+	return i + 2
+}
+
+// Value 는 row 에서 col 열의 값(앞뒤 공백 제거)을 돌려준다.
+// 헤더에 없는 열이거나 행의 열 수가 부족하면 빈 문자열이다.
+func (t *Table) Value(row []string, col Column) string {
+	idx, ok := t.index[col]
+	if !ok || idx >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[idx])
+}
@@ -0,0 +1,319 @@
+package Command_Line
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"FCU_Tools/Config"
+	"FCU_Tools/Metric"
+	"FCU_Tools/Public_data"
+	"FCU_Tools/Run_Manifest"
+	"FCU_Tools/Stage_Graph"
+)
+
+// Options 는 명령행 플래그 값이다. 설정 파일(--config)보다 우선한다.
+// 플래그와 설정 파일 어디에도 없는 입력은 실행 전에 표준 입력으로 물어본다(대화형 대체 입력).
+type Options struct {
+	ConfigPath string // 프로젝트 설정 파일(JSON)
+	AswPath    string // asw.csv 파일 경로 (또는 asw.csv가 들어 있는 폴더)
+	ModelsDir  string // M1: <Folder>/<Folder>.slx (또는 .mdl) 모델들이 들어 있는 루트 폴더
+	M2Dir      string // M2: complexity.json, rq_versus_component.csv 폴더
+	M3Dir      string // M3~M6: component_info.csv 폴더
+	OutDir     string // result.ldi.xml 출력 폴더 (기본값: <작업 디렉토리>/Output)
+	Metrics    string // 실행(병합)할 지표 목록, 예: "m3,m6" (없으면 설정에서 켜진 지표)
+	Jobs       int    // 동시에 분석할 작업 수 (-1: 설정 파일 값 사용)
+
+	Command string // 실행한 하위 명령 이름 (run.json 기록용, 플래그 아님)
+}
+
+// command 는 하나의 하위 명령을 나타낸다.
+// flags 에는 그 명령이 받는 플래그 이름만 적는다.
+type command struct {
+	name  string
+	usage string
+	flags []string
+	run   func(cfg *Config.Config, opts *Options) error
+}
+
+// allCommands 는 하위 명령 목록이다. 지표 명령(m1, m2, ...)과 run 의 입력 플래그는 Metric 레지스트리에서 만든다.
+func allCommands() []command {
+	metrics := Metric.All()
+	cmds := []command{
+		{"run", "의존관계 분석 후 등록된 지표(또는 --metrics로 고른 지표)를 실행한다", withFlags([]string{"config", "metrics", "jobs"}, metrics...), runAll},
+		{"deps", "Output을 초기화하고 asw.csv로 SWC 의존관계(result.ldi.xml)를 생성한다", []string{"config", Metric.AswCsv.Flag, "out"}, runDeps},
+	}
+	for _, m := range metrics {
+		name := m.Name()
+		cmds = append(cmds, command{
+			name:  strings.ToLower(name),
+			usage: fmt.Sprintf("%s 지표를 계산하여 기존 result.ldi.xml에 병합한다", name),
+			flags: withFlags([]string{"config", "jobs"}, m),
+			run:   func(cfg *Config.Config, opts *Options) error { return runSingle(cfg, opts, name) },
+		})
+	}
+	cmds = append(cmds, command{"merge", "지표 출력 폴더(<WorkDir>/Mx/output)에 남아 있는 LDI를 다시 result.ldi.xml에 병합한다", []string{"config", "metrics", "out"}, runMerge})
+	return cmds
+}
+
+// withFlags 는 base 뒤에 지표들이 필요로 하는 입력의 플래그(중복 제외)와 out 을 붙인다.
+func withFlags(base []string, metrics ...Metric.Metric) []string {
+	flags := append([]string(nil), base...)
+	seen := map[string]bool{}
+	for _, m := range metrics {
+		for _, in := range m.Inputs() {
+			if in.Flag != "" && !seen[in.Flag] {
+				seen[in.Flag] = true
+				flags = append(flags, in.Flag)
+			}
+		}
+	}
+	return append(flags, "out")
+}
+
+// Run 은 명령행 인자를 해석하여 하위 명령을 실행하고 프로세스 종료 코드를 돌려준다.
+// 인자가 없으면 기존처럼 대화형으로 전체 파이프라인(run)을 실행한다.
+func Run(args []string) int {
+	name := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name = args[0]
+		args = args[1:]
+	}
+
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage()
+		return 0
+	}
+
+	var cmd *command
+	commands := allCommands()
+	for i := range commands {
+		if commands[i].name == name {
+			cmd = &commands[i]
+			break
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "알 수 없는 명령입니다: %s\n\n", name)
+		printUsage()
+		return 2
+	}
+
+	opts := &Options{Command: cmd.name, Jobs: -1}
+	fs := newFlagSet(cmd, opts)
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "불필요한 인자가 있습니다: %s\n", strings.Join(fs.Args(), " "))
+		return 2
+	}
+
+	cfg, err := loadConfig(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 2
+	}
+
+	if err := cmd.run(cfg, opts); err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	return 0
+}
+
+// newFlagSet 은 명령이 사용하는 플래그만 등록한 FlagSet을 만든다.
+func newFlagSet(cmd *command, opts *Options) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	for _, f := range cmd.flags {
+		switch f {
+		case "config":
+			fs.StringVar(&opts.ConfigPath, "config", "", "프로젝트 설정 파일(JSON) 경로")
+		case "metrics":
+			fs.StringVar(&opts.Metrics, "metrics", "", "실행할 지표 목록 (예: m3,m6; 기본값: 설정에서 켜진 지표)")
+		case "asw":
+			fs.StringVar(&opts.AswPath, "asw", "", "asw.csv 파일 경로 (또는 asw.csv가 있는 폴더)")
+		case "models":
+			fs.StringVar(&opts.ModelsDir, "models", "", "SLX/MDL 모델 루트 폴더 (<Folder>/<Folder>.slx 또는 .mdl)")
+		case "m2-dir":
+			fs.StringVar(&opts.M2Dir, "m2-dir", "", "complexity.json, rq_versus_component.csv가 있는 폴더")
+		case "m3-dir":
+			fs.StringVar(&opts.M3Dir, "m3-dir", "", "component_info.csv가 있는 폴더")
+		case "out":
+			fs.StringVar(&opts.OutDir, "out", "", "result.ldi.xml 출력 폴더 (기본값: ./Output)")
+		case "jobs":
+			fs.IntVar(&opts.Jobs, "jobs", -1, "동시에 분석할 작업 수 (M1: 모델 수, 0: CPU 수; 기본값: 설정 파일의 jobs)")
+		}
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "사용법: %s %s [플래그]\n  %s\n\n", programName(), cmd.name, cmd.usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "사용법: %s <명령> [플래그]\n\n명령:\n", programName())
+	for _, c := range allCommands() {
+		fmt.Fprintf(os.Stderr, "  %-6s %s\n", c.name, c.usage)
+	}
+	fmt.Fprintf(os.Stderr, "\n입력 경로는 --config 설정 파일로 지정하고, 플래그로 덮어쓸 수 있다.\n")
+	fmt.Fprintf(os.Stderr, "둘 다 없는 입력은 실행 전에 표준 입력으로 물어본다.\n")
+	fmt.Fprintf(os.Stderr, "각 명령의 플래그는 '%s <명령> -h'로 확인한다.\n", programName())
+}
+
+func programName() string {
+	return filepath.Base(os.Args[0])
+}
+
+// loadConfig 는 설정 파일(없으면 기본 설정)을 읽고 명령행 플래그 값을 덮어쓴다.
+// 플래그의 상대 경로는 현재 작업 디렉토리 기준이다.
+func loadConfig(opts *Options) (*Config.Config, error) {
+	cfg := Config.Default()
+	if opts.ConfigPath != "" {
+		loaded, err := Config.Load(opts.ConfigPath)
+		if err != nil {
+			return nil, err
+		}
+		cfg = loaded
+	}
+
+	if opts.AswPath != "" {
+		cfg.Inputs.AswCsv = aswFilePath(opts.AswPath)
+	}
+	if opts.ModelsDir != "" {
+		cfg.Inputs.ModelsDir = opts.ModelsDir
+	}
+	if opts.M2Dir != "" {
+		cfg.SetM2InputDir(opts.M2Dir)
+	}
+	if opts.M3Dir != "" {
+		cfg.SetM3InputDir(opts.M3Dir)
+	}
+	if opts.OutDir != "" {
+		cfg.OutputDir = opts.OutDir
+	}
+	if opts.Jobs != -1 {
+		cfg.Jobs = opts.Jobs
+	}
+
+	if err := cfg.Finalize(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// aswFilePath 는 폴더가 주어지면 그 안의 asw.csv 경로로 바꾼다.
+func aswFilePath(path string) string {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return filepath.Join(path, "asw.csv")
+	}
+	return path
+}
+
+// ======================== 하위 명령 ========================
+
+// runAll 은 기존 main 의 전체 흐름이다: 의존관계 분석 후 선택한 지표를 실행한다.
+// --metrics 가 없으면 설정에서 켜진 지표를 모두 실행한다.
+// 필요한 입력은 실행 전에 모두 확인하고, 모든 지표 계산이 성공한 뒤에만 주 LDI에 병합한다.
+func runAll(cfg *Config.Config, opts *Options) error {
+	names, err := selectMetrics(cfg, opts)
+	if err != nil {
+		return err
+	}
+	return runPlan(cfg, opts, append([]string{Stage_Graph.Deps}, names...))
+}
+
+// runDeps 는 Output 폴더를 초기화하고 asw.csv로 SWC 의존관계 LDI를 만든다.
+func runDeps(cfg *Config.Config, opts *Options) error {
+	return runPlan(cfg, opts, []string{Stage_Graph.Deps})
+}
+
+// ---- 단일 지표 명령(m1, m2, ...): 기존 result.ldi.xml에 병합한다 ----
+
+// runSingle 은 Output을 초기화하지 않고 기존 result.ldi.xml이 있는지만 확인한 뒤 지표 하나를 실행한다.
+func runSingle(cfg *Config.Config, opts *Options, metric string) error {
+	if err := Public_data.UseOutputDirectory(cfg.OutputDir); err != nil {
+		return err
+	}
+	return runPlan(cfg, opts, []string{metric}, Stage_Graph.Deps)
+}
+
+// runPlan 은 단계 그래프로 실행 계획을 세우고, 입력을 먼저 확인한 뒤 실행한다.
+// 실행 기록(run.json)은 성공·실패와 관계없이 주 LDI 옆에 남긴다.
+// 이미 끝난 단계(done)가 있으면 기존 result.ldi.xml에 병합하는 실행이므로 그 전 run.json을 이어 붙인다.
+func runPlan(cfg *Config.Config, opts *Options, names []string, done ...string) error {
+	plan, err := Stage_Graph.Plan(names, done...)
+	if err != nil {
+		return err
+	}
+	if err := Stage_Graph.ResolveInputs(cfg, plan); err != nil {
+		return err
+	}
+	rec := Run_Manifest.Start(opts.Command, cfg)
+	if len(done) > 0 {
+		rec.LoadPrevious(cfg.OutputDir)
+	}
+	return finishRun(cfg, rec, Stage_Graph.Execute(cfg, plan, rec))
+}
+
+// finishRun 은 실행 기록을 마치고 <OutputDir>/run.json 에 쓴다. 실행 오류(err)를 그대로 돌려준다.
+func finishRun(cfg *Config.Config, rec *Run_Manifest.Manifest, err error) error {
+	rec.Finish(err)
+	path, werr := rec.Write(cfg.OutputDir)
+	if werr != nil {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "❌", werr)
+			return err
+		}
+		return werr
+	}
+	fmt.Println("📄 실행 기록:", path)
+	return err
+}
+
+// selectMetrics 는 --metrics 목록(없으면 설정에서 켜진 지표)을 돌려준다.
+func selectMetrics(cfg *Config.Config, opts *Options) ([]string, error) {
+	if opts.Metrics != "" {
+		return Stage_Graph.ParseMetrics(opts.Metrics)
+	}
+	return Stage_Graph.EnabledMetrics(cfg), nil
+}
+
+// runMerge 는 각 지표 출력 폴더(<WorkDir>/Mx/output/Mx.ldi.xml)에 이미 생성된 LDI를
+// 다시 계산하지 않고 기존 result.ldi.xml에 병합한다. 출력이 없는 지표는 건너뛴다.
+func runMerge(cfg *Config.Config, opts *Options) error {
+	if err := Public_data.UseOutputDirectory(cfg.OutputDir); err != nil {
+		return err
+	}
+
+	names := Stage_Graph.MetricNames()
+	if opts.Metrics != "" {
+		var err error
+		if names, err = Stage_Graph.ParseMetrics(opts.Metrics); err != nil {
+			return err
+		}
+	}
+
+	var plan []*Stage_Graph.Stage
+	for _, name := range names {
+		st := Stage_Graph.Lookup(name)
+		if _, err := os.Stat(st.Output(cfg)); err != nil {
+			fmt.Printf("ℹ️ %s 출력이 없어 병합하지 않습니다: %s\n", st.Name, st.Output(cfg))
+			continue
+		}
+		plan = append(plan, st)
+	}
+
+	if len(plan) == 0 {
+		return fmt.Errorf("병합할 지표 출력이 없습니다 (<WorkDir>/Mx/output/Mx.ldi.xml)")
+	}
+	rec := Run_Manifest.Start(opts.Command, cfg)
+	rec.LoadPrevious(cfg.OutputDir)
+	return finishRun(cfg, rec, Stage_Graph.MergeOutputs(cfg, plan, rec))
+}
@@ -0,0 +1,607 @@
+package Config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Config 는 한 차량 프로그램(프로젝트)의 분석 설정 전체이다.
+// 설정 파일(JSON)에서 읽어 각 단계에 명시적으로 전달하며, 전역 변수로 경로를 공유하지 않는다.
+//
+// 설정 파일 예시:
+//
+//	{
+//	  "inputs": {
+//	    "asw_csv": "input/asw.csv",
+//	    "models_dir": "models",
+//	    "model_include": ["**/*.slx", "**/*.mdl"],
+//	    "model_exclude": ["**/test/**", "*_harness.slx"],
+//	    "library_dirs": ["libraries"],
+//	    "complexity_json": "input/complexity.json",
+//	    "rq_versus_component_csv": "input/rq_versus_component.csv",
+//	    "component_info_csv": "input/component_info.csv"
+//	  },
+//	  "asw_columns": {
+//	    "component": ["Component", "SWC"],
+//	    "de_op": ["DE_OP", "DataElementOperation"]
+//	  },
+//	  "dependencies": { "many_to_many": "cartesian" },
+//	  "output_dir": "Output",
+//	  "jobs": 4,
+//	  "merge": { "conflict": "keep", "properties": { "coverage.m2": "overwrite" } },
+//	  "metrics": {
+//	    "m1": { "enabled": true, "depth": 3, "model_depth": { "CL1MGR": "auto" } },
+//	    "m3": { "max_layer_distance": 1 },
+//	    "m6": { "asil_levels": { "QM": 0, "A": 1, "B": 2, "C": 3, "D": 4 } }
+//	  }
+//	}
+//
+// 상대 경로는 설정 파일이 있는 폴더를 기준으로 해석한다. 생략한 항목은 Default() 값을 쓴다.
+type Config struct {
+	Inputs       Inputs            `json:"inputs"`
+	AswColumns   AswColumns        `json:"asw_columns"`
+	Dependencies DependencyOptions `json:"dependencies"`
+	OutputDir    string            `json:"output_dir"` // result.ldi.xml 출력 폴더
+	WorkDir      string            `json:"work_dir"`   // M1~M6 중간 산출물(<WorkDir>/Mx/output) 루트 폴더
+	Jobs         int               `json:"jobs"`       // 동시에 분석할 작업 수 (M1: 모델 수). 0 이면 CPU 수
+	Merge        MergeOptions      `json:"merge"`
+	Metrics      Metrics           `json:"metrics"`
+}
+
+// Inputs 는 분석에 필요한 모든 입력 파일 경로이다.
+//
+// M1 모델 찾기:
+//   - model_include / model_exclude 는 models_dir 기준 상대 경로("/" 구분)에 맞추는 glob 이다.
+//     "**" 는 0개 이상의 폴더이고, "/" 가 없는 패턴("*_harness.slx")은 어느 폴더의 파일 이름에도 맞춘다.
+//   - model_list 는 모델 파일 경로를 한 줄에 하나씩 적은 텍스트 파일이다. 상대 경로는 목록 파일의 폴더 기준이고,
+//     빈 줄과 "#" 로 시작하는 줄은 무시한다.
+//   - 둘 다 없으면 기존 규칙(<Folder>/<Folder>.slx, 없으면 .mdl)으로 찾는다.
+//     목록과 glob 을 함께 쓰면 목록의 모델 다음에 glob 으로 찾은 모델을 붙인다. 모델 이름이 겹치면 경로와 함께 경고하고 처음 것만 쓴다.
+type Inputs struct {
+	AswCsv               string   `json:"asw_csv"`                 // 컴포넌트 포트 연결 정보
+	ModelsDir            string   `json:"models_dir"`              // M1: <Folder>/<Folder>.slx (또는 .mdl) 모델 루트
+	ModelInclude         []string `json:"model_include"`           // M1: models_dir 아래를 재귀적으로 찾을 모델 파일 glob (선택, 위 참고)
+	ModelExclude         []string `json:"model_exclude"`           // M1: 찾은 모델 중 제외할 glob (선택)
+	ModelList            string   `json:"model_list"`              // M1: 분석할 모델 파일 목록 (한 줄에 하나, 선택)
+	LibraryDirs          []string `json:"library_dirs"`            // M1: 라이브러리 링크(Reference 블록)의 원본 라이브러리 SLX 폴더 (하위 폴더 포함, 선택)
+	ComplexityJson       string   `json:"complexity_json"`         // M2
+	RqVersusComponentCsv string   `json:"rq_versus_component_csv"` // M2
+	ComponentInfoCsv     string   `json:"component_info_csv"`      // M3~M6
+}
+
+// AswColumns 는 asw.csv의 논리 열마다 허용하는 헤더 이름(별칭) 목록이다.
+// 헤더는 앞에서부터 별칭 순서대로 찾으며 대소문자는 구분하지 않는다.
+// 설정 파일에 적은 목록은 기본 별칭을 통째로 대체한다.
+type AswColumns struct {
+	Component     []string `json:"component"`
+	Runnable      []string `json:"runnable"`
+	Port          []string `json:"port"`
+	PortType      []string `json:"port_type"`
+	InterfaceType []string `json:"interface_type"`
+	DeOp          []string `json:"de_op"`
+}
+
+// N:M 연결 그룹(DE_OP 하나에 제공자와 수신자가 모두 여럿) 처리 정책
+const (
+	ManyToManyCartesian = "cartesian" // 모든 제공자–수신자 쌍을 연결한다
+	ManyToManySkip      = "skip"      // 조용히 건너뛴다 (기존 동작)
+	ManyToManyWarn      = "warn"      // 경고를 출력하고 건너뛴다
+)
+
+// DependencyOptions 는 asw.csv 의존관계 추출 옵션이다.
+type DependencyOptions struct {
+	ManyToMany        string   `json:"many_to_many"`                  // cartesian | skip | warn
+	ClientServerTypes []string `json:"client_server_interface_types"` // C/S 인터페이스로 볼 InterfaceType 값 (오퍼레이션별로 묶음)
+}
+
+// 병합 충돌 정책 (주 LDI 요소에 같은 이름의 속성이 이미 있을 때)
+const (
+	ConflictKeep      = "keep"      // 기존 값을 유지한다 (기존 동작)
+	ConflictOverwrite = "overwrite" // 지표 값으로 덮어쓴다
+	ConflictSum       = "sum"       // 두 값을 숫자로 더한다
+	ConflictError     = "error"     // 값이 다르면 병합을 중단한다
+)
+
+// MergeOptions 는 지표 결과를 주 LDI에 병합하는 방법이다.
+type MergeOptions struct {
+	Conflict     string            `json:"conflict"`      // 기본 충돌 정책: keep | overwrite | sum | error
+	Properties   map[string]string `json:"properties"`    // 속성 이름별 충돌 정책 (Conflict 보다 우선)
+	AddUnmatched []string          `json:"add_unmatched"` // 주 LDI에 없는 요소를 새로 추가할 지표 (그 외 지표는 보고만 하고 버린다)
+}
+
+// PolicyFor 는 property 에 적용할 충돌 정책을 돌려준다.
+func (m MergeOptions) PolicyFor(property string) string {
+	if policy, ok := m.Properties[property]; ok {
+		return policy
+	}
+	return m.Conflict
+}
+
+// Metrics 는 지표별 실행 여부와 옵션이다.
+type Metrics struct {
+	M1 M1Options `json:"m1"`
+	M2 M2Options `json:"m2"`
+	M3 M3Options `json:"m3"`
+	M4 M4Options `json:"m4"`
+	M5 M5Options `json:"m5"`
+	M6 M6Options `json:"m6"`
+}
+
+type M1Options struct {
+	Enabled    bool               `json:"enabled"`
+	Depth      M1Depth            `json:"depth"`       // 모델 계층 분석 깊이 (숫자 또는 "auto")
+	ModelDepth map[string]M1Depth `json:"model_depth"` // 모델 이름별 깊이 (Depth 보다 우선)
+	// Levels 는 계층별 블록 선택 규칙이다. Levels[0] 이 L1 이고, 규칙보다 깊은 계층은 마지막 규칙을 쓴다.
+	// 설정 파일에 levels 가 있으면 기본 규칙을 통째로 교체한다 (규칙에 적지 않은 키는 빈 값이다).
+	Levels []M1LevelRule `json:"levels"`
+	// FollowModelRefs 가 true 이면 ModelReference 블록을 입력 모델 디렉토리의 같은 이름 SLX 로 찾아가
+	// 그 모델의 system_root 부터 계층 분석을 이어간다. 찾지 못하거나 순환 참조이면 경고만 하고 펼치지 않는다.
+	FollowModelRefs bool `json:"follow_model_refs"`
+	// ConnectionKinds 는 포트로 셀 연결 종류이다 (M1ConnectionKinds 중에서 고른다).
+	// line 은 <Line> 연결이고, 나머지는 Goto/From·Data Store·버스 신호로 이어진 암묵적 연결이다.
+	ConnectionKinds []string `json:"connection_kinds"`
+	// StateflowComplexity 가 true 이면 Stateflow chart 요소에 상태 수·전이 수·상태 중첩 깊이를
+	// LDI 속성(m1.sf_states / m1.sf_transitions / m1.sf_depth)으로 쓴다.
+	StateflowComplexity bool `json:"stateflow_complexity"`
+	// Variants 는 Variant Subsystem 과 Variant Source / Sink 블록을 계산에 넣는 방법이다.
+	Variants M1VariantOptions `json:"variants"`
+	// Formula 는 M1 계산식의 계수이다 (M1Formula 참고). 기본값은 기존 계산식(L1 의 C-S 포트만 1.2 배)과 같다.
+	Formula M1Formula `json:"formula"`
+	// Extract 가 true 이면 분석과 별도로 SLX를 <WorkDir>/M1/build 에 복사·압축 해제해 둔다 (XML 확인용).
+	// 분석 자체는 항상 SLX(zip)를 제자리에서 읽는다.
+	Extract bool `json:"extract"`
+	// SLX(zip) 안전 한도 (MB). 항목 하나 또는 SLX 하나에서 읽은 총량이 넘으면 그 SLX를 거부한다.
+	MaxEntryMB int64 `json:"max_entry_mb"`
+	MaxTotalMB int64 `json:"max_total_mb"`
+	// WriteTxt 가 true 이면 모델 계층 분석 결과(M1/output/ir/<모델>.json)를 사람이 읽기 쉬운
+	// M1/output/txt/<모델>.txt 로도 쓴다. 계산에는 쓰지 않는다.
+	WriteTxt bool `json:"write_txt"`
+}
+
+// M1ConnectionKinds 는 metrics.m1.connection_kinds 에 쓸 수 있는 연결 종류이다.
+//   - line        : <Line> 의 Src → Dst
+//   - goto        : Goto → From (GotoTag 와 local / scoped / global 가시 범위)
+//   - datastore   : Data Store Write → Data Store Read (DataStoreName 과 Data Store Memory 범위)
+//   - bus_element : Bus Creator 입력 → Bus Selector 가 고른 같은 이름의 신호
+var M1ConnectionKinds = []string{"line", "goto", "datastore", "bus_element"}
+
+// M1VariantOptions 는 Variant Subsystem / Variant Source·Sink 처리 방법이다.
+//   - Mode     : M1VariantModes 중 하나 (기본 all)
+//   - Controls : 활성 variant 구성. variant 제어 변수 이름 → 값 (예: {"VAR_TRIM": "2"}).
+//     VariantControl 조건식(VAR_TRIM == 2 && ...)을 이 값으로 평가해 활성 choice 를 고른다.
+//     label 모드 블록은 블록에 저장된 활성 label 을 쓴다.
+type M1VariantOptions struct {
+	Mode     string            `json:"mode"`
+	Controls map[string]string `json:"controls"`
+}
+
+// M1VariantModes 는 metrics.m1.variants.mode 에 쓸 수 있는 값이다.
+//   - all        : 모든 choice 와 Variant Source / Sink 의 모든 연결을 센다 (기존 동작)
+//   - active     : 활성 choice 와 활성 연결만 센다. 활성 choice 를 정할 수 없으면 경고하고 모두 센다
+//   - worst_case : Variant Subsystem 마다 포트가 가장 많은 choice 하나만 센다 (Variant Source / Sink 는 모든 연결)
+const (
+	VariantAll       = "all"
+	VariantActive    = "active"
+	VariantWorstCase = "worst_case"
+)
+
+var M1VariantModes = []string{VariantAll, VariantActive, VariantWorstCase}
+
+// M1Formula 는 M1 계산식의 계수이다. 요소마다 M1 = P × N × C 로 계산한다.
+//   - P : 요소 자신의 포트 가중합 (포트 종류별 개수 × 그 계층의 계수)
+//   - N : 다음 계층 요소 수
+//   - C : 다음 계층 요소들의 P 합 (각 요소 계층의 계수로 계산)
+//
+// 모델 루트 인터페이스(graphicalInterface.xml)의 S-R 포트는 따로 세지 않는다. system_root.xml 의 Inport / Outport 로
+// 이미 L1 요소의 포트(sr)에 들어가 있기 때문이며, 루트 인터페이스는 중간 표현(interface)에 기록하고 대조만 한다.
+// C-S 함수(RequireFunction / ProvideFunction)는 cs 포트로 L1 마지막 요소에 더한다.
+//
+// PortWeights[0] 이 L1 의 계수이고, 목록보다 깊은 계층은 마지막 것을 쓴다.
+// 계수를 적지 않은 포트 종류는 1 이다. 설정 파일에 port_weights 가 있으면 기본값을 통째로 교체한다.
+type M1Formula struct {
+	PortWeights []M1PortWeights `json:"port_weights"`
+}
+
+// M1PortWeights 는 한 계층의 포트 종류(M1PortKinds) → 계수이다.
+type M1PortWeights map[string]float64
+
+// M1 포트 종류. 포트 하나는 M1PortKinds 순서대로 처음 맞는 종류 하나로 센다.
+//   - trigger / enable / function_call / ifaction : 블록의 제어 포트에 연결된 포트
+//   - cs       : C-S 포트 (graphicalInterface.xml, L1 에만 있다)
+//   - implicit : Goto/From·Data Store·버스 신호로 만든 가상 포트
+//   - virtual  : 블록-블록 <Line> 연결로 만든 가상 포트
+//   - sr       : 그 밖의 포트 (같은 system 의 Inport / Outport)
+const (
+	PortKindTrigger      = "trigger"
+	PortKindEnable       = "enable"
+	PortKindFunctionCall = "function_call"
+	PortKindIfAction     = "ifaction"
+	PortKindCS           = "cs"
+	PortKindImplicit     = "implicit"
+	PortKindVirtual      = "virtual"
+	PortKindSR           = "sr"
+)
+
+var M1PortKinds = []string{
+	PortKindTrigger, PortKindEnable, PortKindFunctionCall, PortKindIfAction,
+	PortKindCS, PortKindImplicit, PortKindVirtual, PortKindSR,
+}
+
+// Weight 는 kind 포트의 계수를 돌려준다. 적지 않은 종류는 1 이다.
+func (w M1PortWeights) Weight(kind string) float64 {
+	if v, ok := w[kind]; ok {
+		return v
+	}
+	return 1
+}
+
+// WeightsFor 는 level 계층(1 부터)의 포트 계수를 돌려준다.
+func (f M1Formula) WeightsFor(level int) M1PortWeights {
+	if level > len(f.PortWeights) {
+		return f.PortWeights[len(f.PortWeights)-1]
+	}
+	return f.PortWeights[level-1]
+}
+
+// M1Depth 는 M1 계층 분석 깊이이다. JSON 에서는 1 이상의 숫자 또는 "auto"로 쓴다.
+// auto 는 더 이상 하위 시스템(system_<SID>.xml)이 없을 때까지 내려간다.
+type M1Depth int
+
+// DepthAuto 는 깊이 제한 없음("auto")이다.
+const DepthAuto M1Depth = -1
+
+// Reaches 는 level 계층까지 분석하는지 돌려준다.
+func (d M1Depth) Reaches(level int) bool {
+	return d == DepthAuto || level <= int(d)
+}
+
+func (d M1Depth) String() string {
+	if d == DepthAuto {
+		return "auto"
+	}
+	return strconv.Itoa(int(d))
+}
+
+func (d M1Depth) MarshalJSON() ([]byte, error) {
+	if d == DepthAuto {
+		return []byte(`"auto"`), nil
+	}
+	return []byte(strconv.Itoa(int(d))), nil
+}
+
+func (d *M1Depth) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		if s != "auto" {
+			return fmt.Errorf("깊이는 숫자 또는 \"auto\"여야 합니다: %q", s)
+		}
+		*d = DepthAuto
+		return nil
+	}
+	var n int
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("깊이는 숫자 또는 \"auto\"여야 합니다: %s", data)
+	}
+	*d = M1Depth(n)
+	return nil
+}
+
+// DepthFor 는 model 에 적용할 분석 깊이를 돌려준다.
+func (o M1Options) DepthFor(model string) M1Depth {
+	if d, ok := o.ModelDepth[model]; ok {
+		return d
+	}
+	return o.Depth
+}
+
+// M1LevelRule 은 한 계층에서 분석 대상으로 고를 블록의 조건이다.
+//   - BlockTypes        : 고를 BlockType 목록 (비어 있으면 모두)
+//   - ExcludeBlockTypes : 제외할 BlockType 목록
+//   - RequirePorts      : Ports 가 비었거나 PortCounts 가 비어 있는 블록(포트 없는 SubSystem)을 제외
+type M1LevelRule struct {
+	BlockTypes        []string `json:"block_types"`
+	ExcludeBlockTypes []string `json:"exclude_block_types"`
+	RequirePorts      bool     `json:"require_ports"`
+}
+
+// Selects 는 blockType 이 이 규칙의 BlockType 조건에 맞는지 돌려준다.
+func (r M1LevelRule) Selects(blockType string) bool {
+	for _, t := range r.ExcludeBlockTypes {
+		if t == blockType {
+			return false
+		}
+	}
+	if len(r.BlockTypes) == 0 {
+		return true
+	}
+	for _, t := range r.BlockTypes {
+		if t == blockType {
+			return true
+		}
+	}
+	return false
+}
+
+// LevelRule 은 level 계층(1부터)에 적용할 블록 선택 규칙이다.
+func (o M1Options) LevelRule(level int) M1LevelRule {
+	if level > len(o.Levels) {
+		return o.Levels[len(o.Levels)-1]
+	}
+	return o.Levels[level-1]
+}
+
+type M2Options struct {
+	Enabled            bool   `json:"enabled"`
+	RequirementPattern string `json:"requirement_pattern"` // complexity.json key에서 요구사항 ID를 뽑는 정규식
+}
+
+type M3Options struct {
+	Enabled          bool `json:"enabled"`
+	MaxLayerDistance int  `json:"max_layer_distance"` // 허용되는 최대 Layer 차이
+}
+
+type M4Options struct {
+	Enabled bool `json:"enabled"`
+}
+
+type M5Options struct {
+	Enabled    bool   `json:"enabled"`
+	SplitValue string `json:"split_value"` // component_info.csv 의 ASIL 분리 열에서 "분리됨"을 뜻하는 값
+}
+
+type M6Options struct {
+	Enabled    bool           `json:"enabled"`
+	AsilLevels map[string]int `json:"asil_levels"` // ASIL 등급 → 숫자 등급
+}
+
+// Default 는 기존 도구의 동작과 같은 기본 설정을 돌려준다.
+// 입력 경로는 비어 있으며, 출력은 현재 작업 디렉토리 기준이다.
+func Default() *Config {
+	return &Config{
+		AswColumns: AswColumns{
+			Component:     []string{"Component"},
+			Runnable:      []string{"Runnable"},
+			Port:          []string{"Port", "PortName"},
+			PortType:      []string{"PortType", "Port Type"},
+			InterfaceType: []string{"InterfaceType", "Interface Type"},
+			DeOp:          []string{"DE_OP", "DEOP"},
+		},
+		Dependencies: DependencyOptions{
+			ManyToMany:        ManyToManyWarn,
+			ClientServerTypes: []string{"CS", "C/S", "ClientServer"},
+		},
+		OutputDir: "Output",
+		WorkDir:   ".",
+		Merge: MergeOptions{
+			Conflict: ConflictKeep,
+			// M1 요소는 모델 계층 이름(CL1CM1.CL1CM1CLS1 등)이라 주 LDI에 없으므로 새로 추가한다.
+			AddUnmatched: []string{"M1"},
+		},
+		Metrics: Metrics{
+			M1: M1Options{
+				Enabled: true,
+				Depth:   3,
+				Levels: []M1LevelRule{
+					{BlockTypes: []string{"SubSystem", "ModelReference"}, RequirePorts: true}, // L1: 포트 있는 SubSystem / 참조 모델
+					{BlockTypes: []string{"SubSystem", "ModelReference"}},                     // L2: 모든 SubSystem / 참조 모델
+					{ExcludeBlockTypes: []string{"Inport", "Outport"}},                        // L3~: Inport/Outport 외 모든 블록
+				},
+				FollowModelRefs: true,
+				ConnectionKinds: append([]string(nil), M1ConnectionKinds...),
+				Variants:        M1VariantOptions{Mode: VariantAll},
+				MaxEntryMB:      256,
+				MaxTotalMB:      1024,
+				Formula: M1Formula{PortWeights: []M1PortWeights{
+					{PortKindCS: 1.2}, // L1: C-S 포트 1.2 배
+					{},                // L2~: 모두 1
+				}},
+			},
+			M2: M2Options{Enabled: true, RequirementPattern: `^\[[^\]]+\]`},
+			M3: M3Options{Enabled: true, MaxLayerDistance: 1},
+			M4: M4Options{Enabled: true},
+			M5: M5Options{Enabled: true, SplitValue: "Y"},
+			M6: M6Options{Enabled: true, AsilLevels: map[string]int{"A": 1, "B": 2, "C": 3, "D": 4}},
+		},
+	}
+}
+
+// Load 는 설정 파일을 읽어 Default() 위에 덮어쓴다.
+// 알 수 없는 키는 오타로 보고 오류를 낸다.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("설정 파일 읽기 실패 [%s]: %v", path, err)
+	}
+
+	cfg := Default()
+	// 기본 ASIL 매핑, M1 계층 규칙과 포트 계수는 설정 파일에 있으면 통째로 교체한다.
+	// (json 은 기존 슬라이스 원소를 지우지 않고 그 위에 덮어쓰므로, 빠진 키가 기본 규칙의 값으로 남지 않게 비워 둔다)
+	cfg.Metrics.M6.AsilLevels = nil
+	cfg.Metrics.M1.Levels = nil
+	cfg.Metrics.M1.Formula.PortWeights = nil
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("설정 파일 해석 실패 [%s]: %v", path, err)
+	}
+	if cfg.Metrics.M6.AsilLevels == nil {
+		cfg.Metrics.M6.AsilLevels = Default().Metrics.M6.AsilLevels
+	}
+	if cfg.Metrics.M1.Levels == nil {
+		cfg.Metrics.M1.Levels = Default().Metrics.M1.Levels
+	}
+	if cfg.Metrics.M1.Formula.PortWeights == nil {
+		cfg.Metrics.M1.Formula.PortWeights = Default().Metrics.M1.Formula.PortWeights
+	}
+
+	base, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("설정 파일 경로 해석 실패 [%s]: %v", path, err)
+	}
+	cfg.resolvePaths(base)
+	return cfg, nil
+}
+
+// Finalize 는 명령행 값까지 반영한 뒤 호출한다.
+// 남은 상대 경로를 현재 작업 디렉토리 기준 절대 경로로 바꾸고 옵션 값을 검사한다.
+func (c *Config) Finalize() error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("현재 작업 디렉토리를 가져오지 못했습니다: %v", err)
+	}
+	c.resolvePaths(wd)
+	return c.Validate()
+}
+
+// Validate 는 지표 옵션 값이 유효한지 검사한다. 입력 파일 존재 여부는 각 단계가 검사한다.
+func (c *Config) Validate() error {
+	if c.OutputDir == "" {
+		return fmt.Errorf("output_dir가 비어 있습니다")
+	}
+	cols := map[string][]string{
+		"component":      c.AswColumns.Component,
+		"runnable":       c.AswColumns.Runnable,
+		"port":           c.AswColumns.Port,
+		"port_type":      c.AswColumns.PortType,
+		"interface_type": c.AswColumns.InterfaceType,
+		"de_op":          c.AswColumns.DeOp,
+	}
+	for key, names := range cols {
+		if len(names) == 0 {
+			return fmt.Errorf("asw_columns.%s가 비어 있습니다", key)
+		}
+	}
+	switch c.Dependencies.ManyToMany {
+	case ManyToManyCartesian, ManyToManySkip, ManyToManyWarn:
+	default:
+		return fmt.Errorf("dependencies.many_to_many는 %s, %s, %s 중 하나여야 합니다: %q",
+			ManyToManyCartesian, ManyToManySkip, ManyToManyWarn, c.Dependencies.ManyToMany)
+	}
+	policies := map[string]string{"merge.conflict": c.Merge.Conflict}
+	for name, policy := range c.Merge.Properties {
+		policies["merge.properties."+name] = policy
+	}
+	for key, policy := range policies {
+		switch policy {
+		case ConflictKeep, ConflictOverwrite, ConflictSum, ConflictError:
+		default:
+			return fmt.Errorf("%s는 %s, %s, %s, %s 중 하나여야 합니다: %q",
+				key, ConflictKeep, ConflictOverwrite, ConflictSum, ConflictError, policy)
+		}
+	}
+	if c.Jobs < 0 {
+		return fmt.Errorf("jobs는 0 이상이어야 합니다: %d", c.Jobs)
+	}
+	if d := c.Metrics.M1.Depth; d < 1 && d != DepthAuto {
+		return fmt.Errorf("metrics.m1.depth는 1 이상 또는 \"auto\"여야 합니다: %d", d)
+	}
+	for model, d := range c.Metrics.M1.ModelDepth {
+		if d < 1 && d != DepthAuto {
+			return fmt.Errorf("metrics.m1.model_depth.%s는 1 이상 또는 \"auto\"여야 합니다: %d", model, d)
+		}
+	}
+	if len(c.Metrics.M1.Levels) == 0 {
+		return fmt.Errorf("metrics.m1.levels가 비어 있습니다")
+	}
+	if len(c.Metrics.M1.ConnectionKinds) == 0 {
+		return fmt.Errorf("metrics.m1.connection_kinds가 비어 있습니다")
+	}
+	for _, k := range c.Metrics.M1.ConnectionKinds {
+		if !slices.Contains(M1ConnectionKinds, k) {
+			return fmt.Errorf("metrics.m1.connection_kinds는 %s 중에서 골라야 합니다: %q",
+				strings.Join(M1ConnectionKinds, ", "), k)
+		}
+	}
+	for _, pattern := range append(slices.Clip(c.Inputs.ModelInclude), c.Inputs.ModelExclude...) {
+		for _, seg := range strings.Split(pattern, "/") {
+			if _, err := path.Match(seg, ""); err != nil || pattern == "" {
+				return fmt.Errorf("inputs.model_include / model_exclude 패턴 오류: %q", pattern)
+			}
+		}
+	}
+	if !slices.Contains(M1VariantModes, c.Metrics.M1.Variants.Mode) {
+		return fmt.Errorf("metrics.m1.variants.mode는 %s 중 하나여야 합니다: %q",
+			strings.Join(M1VariantModes, ", "), c.Metrics.M1.Variants.Mode)
+	}
+	if len(c.Metrics.M1.Formula.PortWeights) == 0 {
+		return fmt.Errorf("metrics.m1.formula.port_weights가 비어 있습니다")
+	}
+	for i, w := range c.Metrics.M1.Formula.PortWeights {
+		for kind, v := range w {
+			if !slices.Contains(M1PortKinds, kind) {
+				return fmt.Errorf("metrics.m1.formula.port_weights[%d]의 포트 종류는 %s 중 하나여야 합니다: %q",
+					i, strings.Join(M1PortKinds, ", "), kind)
+			}
+			if v < 0 {
+				return fmt.Errorf("metrics.m1.formula.port_weights[%d].%s는 0 이상이어야 합니다: %g", i, kind, v)
+			}
+		}
+	}
+	if c.Metrics.M1.MaxEntryMB < 1 || c.Metrics.M1.MaxTotalMB < 1 {
+		return fmt.Errorf("metrics.m1.max_entry_mb / max_total_mb는 1 이상이어야 합니다: %d / %d",
+			c.Metrics.M1.MaxEntryMB, c.Metrics.M1.MaxTotalMB)
+	}
+	if _, err := regexp.Compile(c.Metrics.M2.RequirementPattern); err != nil {
+		return fmt.Errorf("metrics.m2.requirement_pattern 정규식 오류: %v", err)
+	}
+	if c.Metrics.M3.MaxLayerDistance < 0 {
+		return fmt.Errorf("metrics.m3.max_layer_distance는 0 이상이어야 합니다: %d", c.Metrics.M3.MaxLayerDistance)
+	}
+	if len(c.Metrics.M6.AsilLevels) == 0 {
+		return fmt.Errorf("metrics.m6.asil_levels가 비어 있습니다")
+	}
+	return nil
+}
+
+// MainLDIPath 는 주 LDI(result.ldi.xml) 경로이다.
+func (c *Config) MainLDIPath() string {
+	return filepath.Join(c.OutputDir, "result.ldi.xml")
+}
+
+// StageOutputDir 는 지표(M1~M6)의 출력 디렉토리 <WorkDir>/<stage>/output 경로이다.
+func (c *Config) StageOutputDir(stage string) string {
+	return filepath.Join(c.WorkDir, stage, "output")
+}
+
+// SetM2InputDir 는 complexity.json, rq_versus_component.csv가 들어 있는 폴더로 M2 입력을 설정한다.
+func (c *Config) SetM2InputDir(dir string) {
+	c.Inputs.ComplexityJson = filepath.Join(dir, "complexity.json")
+	c.Inputs.RqVersusComponentCsv = filepath.Join(dir, "rq_versus_component.csv")
+}
+
+// SetM3InputDir 는 component_info.csv가 들어 있는 폴더로 M3~M6 입력을 설정한다.
+func (c *Config) SetM3InputDir(dir string) {
+	c.Inputs.ComponentInfoCsv = filepath.Join(dir, "component_info.csv")
+}
+
+// resolvePaths 는 비어 있지 않은 상대 경로를 base 기준 절대 경로로 바꾼다.
+func (c *Config) resolvePaths(base string) {
+	for _, p := range []*string{
+		&c.Inputs.AswCsv,
+		&c.Inputs.ModelsDir,
+		&c.Inputs.ModelList,
+		&c.Inputs.ComplexityJson,
+		&c.Inputs.RqVersusComponentCsv,
+		&c.Inputs.ComponentInfoCsv,
+		&c.OutputDir,
+		&c.WorkDir,
+	} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(base, *p)
+		}
+	}
+	for i, p := range c.Inputs.LibraryDirs {
+		if p != "" && !filepath.IsAbs(p) {
+			c.Inputs.LibraryDirs[i] = filepath.Join(base, p)
+		}
+	}
+}
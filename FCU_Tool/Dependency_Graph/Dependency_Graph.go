@@ -0,0 +1,394 @@
+package Dependency_Graph
+
+import (
+	"sort"
+	"strings"
+
+	"FCU_Tools/ASW_CSV"
+	"FCU_Tools/Config"
+	"FCU_Tools/Public_data"
+)
+
+// NodeKind 는 노드 종류이다.
+type NodeKind string
+
+const (
+	ComponentNode   NodeKind = "component"
+	RunnableNode    NodeKind = "runnable"
+	PortNode        NodeKind = "port"
+	DataElementNode NodeKind = "data_element" // S/R 데이터 엘리먼트 또는 C/S 오퍼레이션 (DE_OP)
+)
+
+// Node 는 그래프의 노드 하나이다.
+// 러너블과 포트는 소속 컴포넌트 안에서만 이름이 유일하므로 Component 와 함께 식별한다.
+type Node struct {
+	Kind      NodeKind
+	Name      string
+	Component string // 러너블/포트의 소속 컴포넌트 (컴포넌트/데이터 엘리먼트는 빈 문자열)
+	Type      string // 포트: P / R / PR, 데이터 엘리먼트: SR / CS
+}
+
+// ID 는 그래프 안에서 노드를 구분하는 키이다.
+func (n *Node) ID() string {
+	return nodeID(n.Kind, n.Component, n.Name)
+}
+
+func nodeID(kind NodeKind, component, name string) string {
+	if component == "" {
+		return string(kind) + ":" + name
+	}
+	return string(kind) + ":" + component + "/" + name
+}
+
+// EdgeKind 는 엣지 종류이다.
+type EdgeKind string
+
+const (
+	OwnsEdge    EdgeKind = "owns"    // 컴포넌트 → 러너블, 컴포넌트 → 포트
+	UsesEdge    EdgeKind = "uses"    // 러너블 → 포트
+	CarriesEdge EdgeKind = "carries" // 포트 → 데이터 엘리먼트
+	DependsEdge EdgeKind = "depends" // 컴포넌트(P 쪽) → 컴포넌트(R 쪽)
+)
+
+// Edge 는 그래프의 엣지 하나이다.
+// DependsEdge 는 DE_OP 하나에서 같은 컴포넌트 쌍을 잇는 연결을 묶은 것으로,
+// Count 는 그 DE_OP 에서의 P–R 포트 쌍 수, SourceRows 는 관련된 asw.csv 행 번호이다.
+type Edge struct {
+	Kind          EdgeKind
+	From          *Node
+	To            *Node
+	InterfaceType string
+	DeOp          string
+	Count         int
+	SourceRows    []int
+}
+
+// Graph 는 asw.csv 하나로 만든 컴포넌트/러너블/포트/데이터 엘리먼트 그래프이다.
+// 실행마다 한 번 Load 하여 의존관계 분석과 각 지표가 함께 쓴다.
+type Graph struct {
+	nodes map[string]*Node
+	order []*Node
+	edges []*Edge
+	out   map[*Node][]*Edge
+	in    map[*Node][]*Edge
+}
+
+func newGraph() *Graph {
+	return &Graph{
+		nodes: make(map[string]*Node),
+		out:   make(map[*Node][]*Edge),
+		in:    make(map[*Node][]*Edge),
+	}
+}
+
+// portRow 는 asw.csv 한 행에서 읽은 포트 연결 정보이다.
+type portRow struct {
+	row           int
+	component     *Node
+	portType      string
+	interfaceType string
+}
+
+// groupKey 는 연결 그룹의 식별자이다.
+// 같은 DE_OP 이름이라도 C/S 오퍼레이션과 S/R 데이터 엘리먼트는 서로 다른 그룹으로 본다.
+type groupKey struct {
+	kind string // "CS" 또는 "SR"
+	deOp string
+}
+
+func (k groupKey) String() string {
+	return k.kind + ":" + k.deOp
+}
+
+// Load 는 설정의 asw.csv(cfg.Inputs.AswCsv)를 한 번 읽어 그래프를 만든다.
+//
+// 처리 과정:
+//  1. 헤더(cfg.AswColumns 별칭)로 열 위치를 찾는다. Component / PortType / InterfaceType / DE_OP 은 필수이다.
+//  2. 각 행마다 컴포넌트, 러너블, 포트, 데이터 엘리먼트 노드와 owns / uses / carries 엣지를 만든다.
+//     C/S 인터페이스(cfg.Dependencies.ClientServerTypes) 행은 오퍼레이션별로 따로 묶는다.
+//  3. 그룹(DE_OP)마다 제공자(P)와 수신자(R)를 나누어 depends 엣지를 만든다 (addDependencies).
+func Load(cfg *Config.Config) (*Graph, error) {
+	table, err := ASW_CSV.Load(cfg.Inputs.AswCsv, cfg.AswColumns,
+		ASW_CSV.Component, ASW_CSV.PortType, ASW_CSV.InterfaceType, ASW_CSV.DeOp)
+	if err != nil {
+		return nil, err
+	}
+
+	csTypes := make(map[string]bool)
+	for _, t := range cfg.Dependencies.ClientServerTypes {
+		csTypes[strings.ToUpper(strings.TrimSpace(t))] = true
+	}
+
+	g := newGraph()
+	groups := make(map[groupKey][]portRow)
+	for i, row := range table.Rows {
+		component := table.Value(row, ASW_CSV.Component)
+		portType := strings.ToUpper(table.Value(row, ASW_CSV.PortType))
+		interfaceType := table.Value(row, ASW_CSV.InterfaceType)
+		deOp := table.Value(row, ASW_CSV.DeOp)
+
+		// 필요한 값이 비어 있는 행(열 수가 부족한 행 포함)은 스킵
+		if component == "" || portType == "" || deOp == "" {
+			continue
+		}
+
+		key := groupKey{kind: "SR", deOp: deOp}
+		if csTypes[strings.ToUpper(interfaceType)] {
+			key.kind = "CS"
+		}
+
+		// Port 열이 없으면 포트 종류와 DE_OP 로 포트 이름을 대신한다
+		portName := table.Value(row, ASW_CSV.Port)
+		if portName == "" {
+			portName = portType + ":" + deOp
+		}
+
+		rowNo := table.RowNumber(i)
+		compNode := g.addNode(&Node{Kind: ComponentNode, Name: component})
+		portNode := g.addNode(&Node{Kind: PortNode, Name: portName, Component: component, Type: portType})
+		deNode := g.addNode(&Node{Kind: DataElementNode, Name: deOp, Type: key.kind})
+		g.addEdge(OwnsEdge, compNode, portNode, "", "", rowNo)
+		g.addEdge(CarriesEdge, portNode, deNode, interfaceType, deOp, rowNo)
+		if runnable := table.Value(row, ASW_CSV.Runnable); runnable != "" {
+			runNode := g.addNode(&Node{Kind: RunnableNode, Name: runnable, Component: component})
+			g.addEdge(OwnsEdge, compNode, runNode, "", "", rowNo)
+			g.addEdge(UsesEdge, runNode, portNode, interfaceType, deOp, rowNo)
+		}
+
+		groups[key] = append(groups[key], portRow{
+			row:           rowNo,
+			component:     compNode,
+			portType:      portType,
+			interfaceType: interfaceType,
+		})
+	}
+
+	g.addDependencies(cfg, groups)
+	return g, nil
+}
+
+// addDependencies 는 연결 그룹마다 제공자(P)와 수신자(R)를 나누어 depends 엣지를 만든다.
+//   - P 포트는 제공자, R 포트는 수신자, PR 포트는 양쪽 모두로 센다.
+//   - 1 P : N R, N P : 1 R 그룹은 모든 P–R 쌍을 연결한다.
+//   - N P : M R (N>1, M>1) 그룹은 cfg.Dependencies.ManyToMany 정책을 따른다:
+//     cartesian = 모든 P–R 쌍을 연결, skip = 건너뜀, warn = 경고를 출력하고 건너뜀.
+//   - 자기 자신으로의 연결은 만들지 않는다. 인터페이스 타입은 P 쪽 값을 쓴다.
+//
+// 그룹은 이름 순서로 처리하므로 엣지 순서와 경고 순서가 실행마다 같다.
+func (g *Graph) addDependencies(cfg *Config.Config, groups map[groupKey][]portRow) {
+	keys := make([]groupKey, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+	for _, key := range keys {
+		var providers []portRow
+		var receivers []portRow
+
+		// P / R / PR 분류
+		for _, p := range groups[key] {
+			switch p.portType {
+			case "P":
+				providers = append(providers, p)
+			case "R":
+				receivers = append(receivers, p)
+			case "PR":
+				providers = append(providers, p)
+				receivers = append(receivers, p)
+			}
+		}
+
+		// P 또는 R이 하나도 없으면 스킵
+		if len(providers) == 0 || len(receivers) == 0 {
+			continue
+		}
+
+		if len(providers) > 1 && len(receivers) > 1 {
+			switch cfg.Dependencies.ManyToMany {
+			case Config.ManyToManyCartesian:
+				// 아래에서 모든 P–R 쌍을 연결
+			case Config.ManyToManyWarn:
+				Public_data.Warnf("N:M 연결 그룹을 건너뜁니다: %s (제공 %d: %s / 수신 %d: %s)",
+					key, len(providers), componentNames(providers), len(receivers), componentNames(receivers))
+				continue
+			default:
+				continue
+			}
+		}
+
+		// 같은 DE_OP 안에서 같은 컴포넌트 쌍은 엣지 하나로 묶고 Count 를 올린다
+		pairs := make(map[[2]*Node]*Edge)
+		for _, p := range providers {
+			for _, r := range receivers {
+				if p.component == r.component {
+					// 자기 자신 의존은 스킵
+					continue
+				}
+				pair := [2]*Node{p.component, r.component}
+				if e, ok := pairs[pair]; ok {
+					e.Count++
+					e.SourceRows = appendRow(appendRow(e.SourceRows, p.row), r.row)
+					continue
+				}
+				pairs[pair] = g.addEdge(DependsEdge, p.component, r.component, p.interfaceType, key.deOp, p.row, r.row)
+			}
+		}
+	}
+}
+
+func componentNames(ports []portRow) string {
+	names := make([]string, 0, len(ports))
+	for _, p := range ports {
+		names = append(names, p.component.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func appendRow(rows []int, row int) []int {
+	for _, r := range rows {
+		if r == row {
+			return rows
+		}
+	}
+	return append(rows, row)
+}
+
+// addNode 는 같은 ID 의 노드가 있으면 그것을, 없으면 n 을 등록하여 돌려준다.
+func (g *Graph) addNode(n *Node) *Node {
+	id := n.ID()
+	if existing, ok := g.nodes[id]; ok {
+		return existing
+	}
+	g.nodes[id] = n
+	g.order = append(g.order, n)
+	return n
+}
+
+// addEdge 는 엣지를 추가한다. owns / uses / carries 처럼 같은 엣지가 여러 행에서 나오면
+// 새 엣지를 만들지 않고 기존 엣지에 행 번호와 Count 를 더한다.
+func (g *Graph) addEdge(kind EdgeKind, from, to *Node, interfaceType, deOp string, rows ...int) *Edge {
+	if kind != DependsEdge {
+		for _, e := range g.out[from] {
+			if e.Kind == kind && e.To == to && e.DeOp == deOp {
+				e.Count++
+				for _, r := range rows {
+					e.SourceRows = appendRow(e.SourceRows, r)
+				}
+				return e
+			}
+		}
+	}
+	e := &Edge{
+		Kind:          kind,
+		From:          from,
+		To:            to,
+		InterfaceType: interfaceType,
+		DeOp:          deOp,
+		Count:         1,
+	}
+	for _, r := range rows {
+		e.SourceRows = appendRow(e.SourceRows, r)
+	}
+	g.edges = append(g.edges, e)
+	g.out[from] = append(g.out[from], e)
+	g.in[to] = append(g.in[to], e)
+	return e
+}
+
+// ======================== 조회 API ========================
+
+// Node 는 종류와 이름(러너블/포트는 소속 컴포넌트 포함)으로 노드를 찾는다. 없으면 nil.
+func (g *Graph) Node(kind NodeKind, component, name string) *Node {
+	return g.nodes[nodeID(kind, component, name)]
+}
+
+// Component 는 이름으로 컴포넌트 노드를 찾는다. 없으면 nil.
+func (g *Graph) Component(name string) *Node {
+	return g.Node(ComponentNode, "", name)
+}
+
+// Nodes 는 kind 종류의 노드를 asw.csv에 처음 나온 순서대로 돌려준다.
+func (g *Graph) Nodes(kind NodeKind) []*Node {
+	var nodes []*Node
+	for _, n := range g.order {
+		if n.Kind == kind {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// Edges 는 kind 종류의 엣지를 추가된 순서대로 돌려준다.
+func (g *Graph) Edges(kind EdgeKind) []*Edge {
+	var edges []*Edge
+	for _, e := range g.edges {
+		if e.Kind == kind {
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+// Dependencies 는 컴포넌트 간 의존 엣지(depends)이다.
+func (g *Graph) Dependencies() []*Edge {
+	return g.Edges(DependsEdge)
+}
+
+// Successors 는 n 에서 나가는 kind 종류의 엣지이다 (kind 가 빈 문자열이면 모든 종류).
+// 예: Successors(comp, DependsEdge) 는 comp 가 제공하는 데이터를 받는 쪽으로의 의존 엣지이다.
+func (g *Graph) Successors(n *Node, kind EdgeKind) []*Edge {
+	return filterKind(g.out[n], kind)
+}
+
+// Predecessors 는 n 으로 들어오는 kind 종류의 엣지이다 (kind 가 빈 문자열이면 모든 종류).
+func (g *Graph) Predecessors(n *Node, kind EdgeKind) []*Edge {
+	return filterKind(g.in[n], kind)
+}
+
+// EdgesByInterfaceType 는 인터페이스 타입(대소문자 무시)이 같은 의존 엣지이다.
+func (g *Graph) EdgesByInterfaceType(interfaceType string) []*Edge {
+	var edges []*Edge
+	for _, e := range g.Dependencies() {
+		if strings.EqualFold(e.InterfaceType, interfaceType) {
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+// Strengths 는 컴포넌트 쌍별 의존 강도(모든 DE_OP 에 걸친 Count 합)이다: from → (to → 강도).
+func (g *Graph) Strengths() map[string]map[string]int {
+	result := make(map[string]map[string]int)
+	for _, e := range g.Dependencies() {
+		from, to := e.From.Name, e.To.Name
+		if result[from] == nil {
+			result[from] = make(map[string]int)
+		}
+		result[from][to] += e.Count
+	}
+	return result
+}
+
+// RunnableComponents 는 러너블 이름 → 소속 컴포넌트 이름 매핑이다.
+// 같은 러너블 이름이 여러 컴포넌트에 있으면 asw.csv에서 나중에 등장한 컴포넌트의 것을 쓴다.
+func (g *Graph) RunnableComponents() map[string]string {
+	result := make(map[string]string)
+	for _, n := range g.Nodes(RunnableNode) {
+		result[n.Name] = n.Component
+	}
+	return result
+}
+
+func filterKind(edges []*Edge, kind EdgeKind) []*Edge {
+	if kind == "" {
+		return edges
+	}
+	var result []*Edge
+	for _, e := range edges {
+		if e.Kind == kind {
+			result = append(result, e)
+		}
+	}
+	return result
+}
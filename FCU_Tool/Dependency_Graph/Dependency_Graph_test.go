@@ -0,0 +1,176 @@
+package Dependency_Graph
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"FCU_Tools/Config"
+	"FCU_Tools/Public_data"
+)
+
+// 행 번호는 헤더가 1행이므로 2행부터이다.
+//   - D1 : 1 P : 2 R
+//   - D2 : 2 P : 2 R (N:M 정책 대상)
+//   - D3 : PR 포트는 제공·수신 양쪽으로 센다. E→E 는 만들지 않는다
+//   - D4 : 같은 컴포넌트 쌍의 포트 쌍 두 개는 엣지 하나로 묶고 Count 2
+//   - OP : 같은 이름의 S/R 데이터 엘리먼트와 C/S 오퍼레이션은 다른 그룹 (한 그룹이면 2:2 로 건너뛰어진다)
+const testCsv = `Component,Runnable,PortType,Port,InterfaceType,DE_OP
+A,rA,P,P_D1,SR,D1
+B,rB,R,R_D1,SR,D1
+C,rC,R,R_D1,SR,D1
+A,rA,P,P_D2,SR,D2
+B,rB,P,P_D2,SR,D2
+C,rC,R,R_D2,SR,D2
+D,rD,R,R_D2,SR,D2
+E,rE,PR,PR_D3,SR,D3
+F,rF,R,R_D3,SR,D3
+A,rA,P,P_OP,SR,OP
+B,rB,R,R_OP,SR,OP
+C,rC,P,P_OP2,CS,OP
+D,rD,R,R_OP2,CS,OP
+E,rE,P,P1_D4,SR,D4
+E,rE,P,P2_D4,SR,D4
+F,rF,R,R_D4,SR,D4
+,rX,P,P_X,SR,D1
+`
+
+func loadTest(t *testing.T, manyToMany string) *Graph {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "asw.csv")
+	if err := os.WriteFile(path, []byte(testCsv), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config.Default()
+	cfg.Inputs.AswCsv = path
+	cfg.Dependencies.ManyToMany = manyToMany
+	g, err := Load(cfg)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return g
+}
+
+// edge 는 비교하기 쉬운 의존 엣지 표현이다.
+type edge struct {
+	From, To, DeOp, InterfaceType string
+	Count                         int
+	Rows                          []int
+}
+
+func dependencies(g *Graph) []edge {
+	var out []edge
+	for _, e := range g.Dependencies() {
+		out = append(out, edge{e.From.Name, e.To.Name, e.DeOp, e.InterfaceType, e.Count, e.SourceRows})
+	}
+	return out
+}
+
+func TestDependencies(t *testing.T) {
+	// 그룹은 "CS:OP", "SR:D1", "SR:D2", ... 순서로 처리한다
+	before := []edge{
+		{"C", "D", "OP", "CS", 1, []int{13, 14}},
+		{"A", "B", "D1", "SR", 1, []int{2, 3}},
+		{"A", "C", "D1", "SR", 1, []int{2, 4}},
+	}
+	after := []edge{
+		{"E", "F", "D3", "SR", 1, []int{9, 10}},
+		{"E", "F", "D4", "SR", 2, []int{15, 17, 16}},
+		{"A", "B", "OP", "SR", 1, []int{11, 12}},
+	}
+	cartesian := []edge{
+		{"A", "C", "D2", "SR", 1, []int{5, 7}},
+		{"A", "D", "D2", "SR", 1, []int{5, 8}},
+		{"B", "C", "D2", "SR", 1, []int{6, 7}},
+		{"B", "D", "D2", "SR", 1, []int{6, 8}},
+	}
+
+	tests := []struct {
+		policy    string
+		edges     []edge
+		strengths map[string]map[string]int
+		warnings  int
+	}{
+		{
+			policy: Config.ManyToManySkip,
+			edges:  append(append([]edge(nil), before...), after...),
+			strengths: map[string]map[string]int{
+				"A": {"B": 2, "C": 1},
+				"C": {"D": 1},
+				"E": {"F": 3},
+			},
+		},
+		{
+			policy: Config.ManyToManyWarn,
+			edges:  append(append([]edge(nil), before...), after...),
+			strengths: map[string]map[string]int{
+				"A": {"B": 2, "C": 1},
+				"C": {"D": 1},
+				"E": {"F": 3},
+			},
+			warnings: 1,
+		},
+		{
+			policy: Config.ManyToManyCartesian,
+			edges:  append(append(append([]edge(nil), before...), cartesian...), after...),
+			strengths: map[string]map[string]int{
+				"A": {"B": 2, "C": 2, "D": 1},
+				"B": {"C": 1, "D": 1},
+				"C": {"D": 1},
+				"E": {"F": 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			start := Public_data.WarningCount()
+			g := loadTest(t, tt.policy)
+			if got := Public_data.WarningCount() - start; got != tt.warnings {
+				t.Errorf("경고 %d 개, 기대 %d 개", got, tt.warnings)
+			}
+			if got := dependencies(g); !reflect.DeepEqual(got, tt.edges) {
+				t.Errorf("Dependencies:\n got %v\nwant %v", got, tt.edges)
+			}
+			if got := g.Strengths(); !reflect.DeepEqual(got, tt.strengths) {
+				t.Errorf("Strengths = %v, 기대 %v", got, tt.strengths)
+			}
+		})
+	}
+}
+
+func TestGraphNodes(t *testing.T) {
+	g := loadTest(t, Config.ManyToManySkip)
+
+	// Component 가 빈 행은 건너뛴다
+	var names []string
+	for _, n := range g.Nodes(ComponentNode) {
+		names = append(names, n.Name)
+	}
+	if want := []string{"A", "B", "C", "D", "E", "F"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("컴포넌트 = %v, 기대 %v", names, want)
+	}
+
+	// 같은 이름의 DE_OP 라도 S/R 과 C/S 는 같은 데이터 엘리먼트 노드이다 (그룹만 나뉜다)
+	if de := g.Node(DataElementNode, "", "OP"); de == nil {
+		t.Error("데이터 엘리먼트 OP 가 없음")
+	}
+	if got := g.RunnableComponents()["rE"]; got != "E" {
+		t.Errorf("rE 의 컴포넌트 = %q", got)
+	}
+	if got := len(g.EdgesByInterfaceType("cs")); got != 1 {
+		t.Errorf("C/S 의존 엣지 %d 개, 기대 1", got)
+	}
+	c := g.Component("C")
+	var succ []string
+	for _, e := range g.Successors(c, DependsEdge) {
+		succ = append(succ, fmt.Sprintf("%s/%s", e.To.Name, e.DeOp))
+	}
+	if want := []string{"D/OP"}; !reflect.DeepEqual(succ, want) {
+		t.Errorf("C 의 의존 = %v, 기대 %v", succ, want)
+	}
+	if got := len(g.Predecessors(c, DependsEdge)); got != 1 {
+		t.Errorf("C 로 들어오는 의존 %d 개, 기대 1 (A/D1)", got)
+	}
+}
@@ -0,0 +1,474 @@
+package LDI
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"FCU_Tools/Public_data"
+)
+
+// Document 는 LDI 파일 하나의 메모리 모델이다.
+//
+//	<ldi>
+//	  <element name="CL1CM1">
+//	    <uses provider="CL1MGR" strength="1"/>
+//	    <property name="coverage.m1">0.5000</property>
+//	    <tag name="library"/>
+//	  </element>
+//	</ldi>
+//
+// 같은 이름의 element 는 하나로 합쳐서 관리한다.
+// 파일로 쓸 때는 항상 같은 순서(element 이름, uses provider, property 이름, tag 이름 순)로 정렬하므로
+// 읽고 다시 쓰면 같은 내용이 나온다.
+//
+// 이 도구가 모르는 속성(Attrs)과 하위 요소(Extra)도 읽은 그대로 보관했다가 다시 쓴다.
+// 다른 도구가 쓴 내용이 병합 중에 사라지지 않도록 하기 위함이다.
+type Document struct {
+	Attrs []xml.Attr
+	Extra []Node
+
+	elements []*Element
+	index    map[string]*Element
+}
+
+// Element 는 LDI 의 <element> 하나이다.
+// TagAttrs 는 tag 이름 → 그 <tag> 의 name 외 속성이다.
+type Element struct {
+	Name       string
+	Uses       []Uses
+	Properties []Property
+	Tags       []string
+	TagAttrs   map[string][]xml.Attr
+	Attrs      []xml.Attr
+	Extra      []Node
+}
+
+// Uses 는 <uses provider="..." strength="..." kind="..."/> 이다. 빈 속성은 쓰지 않는다.
+type Uses struct {
+	Provider string
+	Strength string
+	Kind     string
+	Attrs    []xml.Attr
+}
+
+// Property 는 <property name="...">값</property> 이다.
+type Property struct {
+	Name  string
+	Value string
+	Attrs []xml.Attr
+}
+
+// Node 는 이 패키지가 해석하지 않는 하위 요소이다. 내용(Inner)은 원문 그대로 보관한다.
+// Attrs 와 XMLName 의 Space 는 네임스페이스 URL 이 아니라 원래의 접두사이다 (Parse 가 되돌려 둔다).
+type Node struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Inner   string     `xml:",innerxml"`
+}
+
+// New 는 빈 문서를 만든다.
+func New() *Document {
+	return &Document{index: make(map[string]*Element)}
+}
+
+// ======================== 읽기 ========================
+
+type xmlRoot struct {
+	XMLName  xml.Name     `xml:"ldi"`
+	Attrs    []xml.Attr   `xml:",any,attr"`
+	Elements []xmlElement `xml:"element"`
+	Extra    []Node       `xml:",any"`
+}
+
+type xmlElement struct {
+	Name       string        `xml:"name,attr"`
+	Attrs      []xml.Attr    `xml:",any,attr"`
+	Uses       []xmlUses     `xml:"uses"`
+	Properties []xmlProperty `xml:"property"`
+	Tags       []xmlTag      `xml:"tag"`
+	Extra      []Node        `xml:",any"`
+}
+
+type xmlUses struct {
+	Provider string     `xml:"provider,attr"`
+	Strength string     `xml:"strength,attr"`
+	Kind     string     `xml:"kind,attr"`
+	Attrs    []xml.Attr `xml:",any,attr"`
+}
+
+type xmlProperty struct {
+	Name  string     `xml:"name,attr"`
+	Value string     `xml:",chardata"`
+	Attrs []xml.Attr `xml:",any,attr"`
+}
+
+type xmlTag struct {
+	Name  string     `xml:"name,attr"`
+	Attrs []xml.Attr `xml:",any,attr"`
+}
+
+// Read 는 LDI 파일을 읽는다.
+func Read(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("LDI 파일 읽기 실패 [%s]: %v", path, err)
+	}
+	doc, dups, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("LDI XML 해석 실패 [%s]: %v", path, err)
+	}
+	for _, d := range dups {
+		Public_data.Warnf("LDI [%s]: %s", path, d)
+	}
+	return doc, nil
+}
+
+// Parse 는 LDI XML 내용을 해석한다. 같은 이름의 element 가 여러 번 나오면 하나로 합친다.
+// 중복된 property(또는 strength 가 다른 uses)는 첫 값을 쓰고 나머지는 경고로 알린다.
+func Parse(data []byte) (*Document, error) {
+	doc, dups, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range dups {
+		Public_data.Warnf("LDI: %s", d)
+	}
+	return doc, nil
+}
+
+// parse 는 문서와 함께 무시한 중복 항목의 설명을 돌려준다.
+func parse(data []byte) (*Document, []string, error) {
+	var root xmlRoot
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, nil, err
+	}
+
+	restorePrefixes(&root)
+
+	var dups []string
+	doc := New()
+	doc.Attrs = root.Attrs
+	doc.Extra = root.Extra
+	for _, xe := range root.Elements {
+		el := doc.AddElement(xe.Name)
+		el.Attrs = mergeAttrs(el.Attrs, xe.Attrs)
+		el.Extra = append(el.Extra, xe.Extra...)
+		for _, u := range xe.Uses {
+			if old, ok := el.uses(u.Provider, u.Kind); ok {
+				if old.Strength != u.Strength {
+					dups = append(dups, fmt.Sprintf("element %s 의 uses %s 가 중복됨 (strength %q 사용, %q 무시)",
+						xe.Name, u.Provider, old.Strength, u.Strength))
+				}
+				continue
+			}
+			el.AddUses(Uses{Provider: u.Provider, Strength: u.Strength, Kind: u.Kind, Attrs: u.Attrs})
+		}
+		for _, p := range xe.Properties {
+			if old, ok := el.Property(p.Name); ok {
+				dups = append(dups, fmt.Sprintf("element %s 의 property %s 가 중복됨 (%q 사용, %q 무시)",
+					xe.Name, p.Name, old, p.Value))
+				continue
+			}
+			el.Properties = append(el.Properties, Property{Name: p.Name, Value: p.Value, Attrs: p.Attrs})
+		}
+		for _, t := range xe.Tags {
+			el.AddTag(t.Name)
+			el.setTagAttrs(t.Name, t.Attrs)
+		}
+	}
+	return doc, dups, nil
+}
+
+// xmlNamespace 는 xml: 접두사의 네임스페이스이다 (선언 없이 쓸 수 있다).
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// restorePrefixes 는 encoding/xml 이 네임스페이스 URL 로 바꿔 놓은 이름의 Space 를 원래 접두사로 되돌린다.
+// 문서 안의 xmlns:p="URL" 선언으로 URL → 접두사 표를 만들고 (같은 URL 은 처음 선언한 접두사), 기본 네임스페이스(xmlns="URL")는
+// 접두사 없이 쓴다. 선언되지 않은 접두사는 encoding/xml 이 그대로 두므로 그대로 쓴다.
+func restorePrefixes(root *xmlRoot) {
+	var lists [][]xml.Attr
+	var names []*xml.Name
+	add := func(list []xml.Attr) {
+		lists = append(lists, list)
+		for i := range list {
+			names = append(names, &list[i].Name)
+		}
+	}
+	addNodes := func(nodes []Node) {
+		for i := range nodes {
+			add(nodes[i].Attrs)
+			names = append(names, &nodes[i].XMLName)
+		}
+	}
+	add(root.Attrs)
+	addNodes(root.Extra)
+	for i := range root.Elements {
+		xe := &root.Elements[i]
+		add(xe.Attrs)
+		addNodes(xe.Extra)
+		for _, u := range xe.Uses {
+			add(u.Attrs)
+		}
+		for _, p := range xe.Properties {
+			add(p.Attrs)
+		}
+		for _, t := range xe.Tags {
+			add(t.Attrs)
+		}
+	}
+
+	prefixes := map[string]string{xmlNamespace: "xml"}
+	for _, list := range lists {
+		for _, a := range list {
+			prefix, ok := "", false
+			switch {
+			case a.Name.Space == "xmlns":
+				prefix, ok = a.Name.Local, true
+			case a.Name.Space == "" && a.Name.Local == "xmlns":
+				ok = true
+			}
+			if _, seen := prefixes[a.Value]; ok && !seen {
+				prefixes[a.Value] = prefix
+			}
+		}
+	}
+	for _, n := range names {
+		if prefix, ok := prefixes[n.Space]; ok {
+			n.Space = prefix
+		}
+	}
+}
+
+// ======================== 쓰기 ========================
+
+// Write 는 문서를 path 에 쓴다 (정렬된 순서, XML 특수문자는 이스케이프).
+func (d *Document) Write(path string) error {
+	if err := os.WriteFile(path, d.Bytes(), 0644); err != nil {
+		return fmt.Errorf("LDI 파일 쓰기 실패 [%s]: %v", path, err)
+	}
+	return nil
+}
+
+// Bytes 는 문서를 LDI XML 로 직렬화한다.
+func (d *Document) Bytes() []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString("<ldi" + attrs(d.Attrs) + ">\n")
+	for _, el := range d.Elements() {
+		b.WriteString("  <element name=\"" + escape(el.Name) + "\"" + attrs(el.Attrs) + ">\n")
+
+		uses := append([]Uses(nil), el.Uses...)
+		sort.SliceStable(uses, func(i, j int) bool { return uses[i].Provider < uses[j].Provider })
+		for _, u := range uses {
+			b.WriteString("    <uses provider=\"" + escape(u.Provider) + "\"")
+			if u.Strength != "" {
+				b.WriteString(" strength=\"" + escape(u.Strength) + "\"")
+			}
+			if u.Kind != "" {
+				b.WriteString(" kind=\"" + escape(u.Kind) + "\"")
+			}
+			b.WriteString(attrs(u.Attrs) + "/>\n")
+		}
+
+		props := append([]Property(nil), el.Properties...)
+		sort.SliceStable(props, func(i, j int) bool { return props[i].Name < props[j].Name })
+		for _, p := range props {
+			b.WriteString("    <property name=\"" + escape(p.Name) + "\"" + attrs(p.Attrs) + ">" + escape(p.Value) + "</property>\n")
+		}
+
+		tags := append([]string(nil), el.Tags...)
+		sort.Strings(tags)
+		for _, t := range tags {
+			b.WriteString("    <tag name=\"" + escape(t) + "\"" + attrs(el.TagAttrs[t]) + "/>\n")
+		}
+
+		for _, n := range el.Extra {
+			b.WriteString("    " + n.xml() + "\n")
+		}
+
+		b.WriteString("  </element>\n")
+	}
+	for _, n := range d.Extra {
+		b.WriteString("  " + n.xml() + "\n")
+	}
+	b.WriteString("</ldi>\n")
+	return b.Bytes()
+}
+
+// 읽은 대로의 요소 (내용이 없으면 <name/>)
+func (n Node) xml() string {
+	start := "<" + qname(n.XMLName) + attrs(n.Attrs)
+	if strings.TrimSpace(n.Inner) == "" {
+		return start + "/>"
+	}
+	return start + ">" + n.Inner + "</" + qname(n.XMLName) + ">"
+}
+
+// attrs 는 속성 목록을 ` a="v"` 형태로 쓴다 (읽은 순서 유지).
+func attrs(list []xml.Attr) string {
+	var b strings.Builder
+	for _, a := range list {
+		b.WriteString(" " + qname(a.Name) + "=\"" + escape(a.Value) + "\"")
+	}
+	return b.String()
+}
+
+// qname 은 접두사(Space)가 있으면 "접두사:이름" 으로 쓴다 (xmlns:p 선언은 Space="xmlns").
+func qname(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+func escape(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// ======================== 모델 조작 ========================
+
+// Elements 는 element 를 이름 순으로 돌려준다.
+func (d *Document) Elements() []*Element {
+	list := append([]*Element(nil), d.elements...)
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Element 는 이름으로 element 를 찾는다. 없으면 nil.
+func (d *Document) Element(name string) *Element {
+	return d.index[name]
+}
+
+// AddElement 는 name element 를 돌려준다. 없으면 새로 만든다.
+func (d *Document) AddElement(name string) *Element {
+	if el, ok := d.index[name]; ok {
+		return el
+	}
+	el := &Element{Name: name}
+	d.elements = append(d.elements, el)
+	d.index[name] = el
+	return el
+}
+
+// RemoveElement 는 name element 를 지운다.
+func (d *Document) RemoveElement(name string) {
+	if _, ok := d.index[name]; !ok {
+		return
+	}
+	delete(d.index, name)
+	for i, el := range d.elements {
+		if el.Name == name {
+			d.elements = append(d.elements[:i], d.elements[i+1:]...)
+			break
+		}
+	}
+}
+
+// Rename 은 element 이름을 바꾼다. 새 이름의 element 가 이미 있으면 내용을 합친다.
+func (d *Document) Rename(oldName, newName string) {
+	el := d.index[oldName]
+	if el == nil || oldName == newName {
+		return
+	}
+	d.RemoveElement(oldName)
+	d.AddElement(newName).Merge(el)
+}
+
+// Merge 는 src 의 uses / tag / 알 수 없는 내용을 추가하고, e 에 없는 property 만 가져온다 (기존 값 유지).
+func (e *Element) Merge(src *Element) {
+	for _, u := range src.Uses {
+		e.AddUses(u)
+	}
+	for _, p := range src.Properties {
+		if _, ok := e.Property(p.Name); !ok {
+			e.Properties = append(e.Properties, p)
+		}
+	}
+	for _, t := range src.Tags {
+		e.AddTag(t)
+		e.setTagAttrs(t, src.TagAttrs[t])
+	}
+	e.Attrs = mergeAttrs(e.Attrs, src.Attrs)
+	e.Extra = append(e.Extra, src.Extra...)
+}
+
+// mergeAttrs 는 dst 에 없는 이름의 속성만 src 에서 덧붙인다.
+func mergeAttrs(dst, src []xml.Attr) []xml.Attr {
+	for _, a := range src {
+		found := false
+		for _, d := range dst {
+			if d.Name == a.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			dst = append(dst, a)
+		}
+	}
+	return dst
+}
+
+func (e *Element) setTagAttrs(tag string, list []xml.Attr) {
+	if len(list) == 0 {
+		return
+	}
+	if e.TagAttrs == nil {
+		e.TagAttrs = make(map[string][]xml.Attr)
+	}
+	e.TagAttrs[tag] = mergeAttrs(e.TagAttrs[tag], list)
+}
+
+// Property 는 name 속성 값을 돌려준다.
+func (e *Element) Property(name string) (string, bool) {
+	for _, p := range e.Properties {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// SetProperty 는 name 속성을 value 로 설정한다 (없으면 추가, 있으면 덮어씀).
+func (e *Element) SetProperty(name, value string) {
+	for i := range e.Properties {
+		if e.Properties[i].Name == name {
+			e.Properties[i].Value = value
+			return
+		}
+	}
+	e.Properties = append(e.Properties, Property{Name: name, Value: value})
+}
+
+// AddUses 는 uses 를 추가한다. 같은 provider/kind 의 uses 가 이미 있으면 추가하지 않는다.
+func (e *Element) AddUses(u Uses) {
+	if _, ok := e.uses(u.Provider, u.Kind); ok {
+		return
+	}
+	e.Uses = append(e.Uses, u)
+}
+
+func (e *Element) uses(provider, kind string) (Uses, bool) {
+	for _, existing := range e.Uses {
+		if existing.Provider == provider && existing.Kind == kind {
+			return existing, true
+		}
+	}
+	return Uses{}, false
+}
+
+// AddTag 는 태그를 추가한다 (중복 무시).
+func (e *Element) AddTag(tag string) {
+	for _, t := range e.Tags {
+		if t == tag {
+			return
+		}
+	}
+	e.Tags = append(e.Tags, tag)
+}
@@ -0,0 +1,95 @@
+package LDI
+
+import (
+	"strings"
+	"testing"
+)
+
+// 이 도구가 모르는 속성 / 요소는 읽고 다시 쓸 때 그대로 남아야 한다
+func TestParseKeepsUnknownContent(t *testing.T) {
+	in := `<?xml version="1.0" encoding="UTF-8"?>
+<ldi version="2" xmlns:x="urn:x" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:noNamespaceSchemaLocation="ldi.xsd">
+  <element name="B" owner="team" x:id="7">
+    <uses provider="A" strength="1" note="n"/>
+    <property name="p" unit="ms">1</property>
+    <tag name="t" color="red"/>
+    <comment lang="ko">메모 <b>굵게</b></comment>
+    <x:note x:kind="a" xml:lang="en">text <x:b/></x:note>
+    <y:raw xmlns:y="urn:y" y:k="1"/>
+    <d xmlns="urn:d"><e/></d>
+  </element>
+  <element name="A"/>
+  <meta tool="other"/>
+</ldi>
+`
+	doc, err := Parse([]byte(in))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	out := string(doc.Bytes())
+	for _, want := range []string{
+		`<ldi version="2" xmlns:x="urn:x" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:noNamespaceSchemaLocation="ldi.xsd">`,
+		`<element name="B" owner="team" x:id="7">`,
+		`<uses provider="A" strength="1" note="n"/>`,
+		`<property name="p" unit="ms">1</property>`,
+		`<tag name="t" color="red"/>`,
+		`<comment lang="ko">메모 <b>굵게</b></comment>`,
+		`<x:note x:kind="a" xml:lang="en">text <x:b/></x:note>`,
+		`<y:raw xmlns:y="urn:y" y:k="1"/>`,
+		`<d xmlns="urn:d"><e/></d>`,
+		`<meta tool="other"/>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("출력에 %s 가 없음:\n%s", want, out)
+		}
+	}
+
+	// 다시 읽고 쓰면 같은 내용
+	again, err := Parse([]byte(out))
+	if err != nil {
+		t.Fatalf("Parse (2회차): %v", err)
+	}
+	if got := string(again.Bytes()); got != out {
+		t.Errorf("두 번째 출력이 다름:\n%s\n---\n%s", got, out)
+	}
+
+	// Rename 후에도 남아 있어야 한다
+	doc.Rename("B", "C")
+	out = string(doc.Bytes())
+	for _, want := range []string{`<element name="C" owner="team" x:id="7">`, `<tag name="t" color="red"/>`, `<comment lang="ko">`, `<x:note x:kind="a"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Rename 후 출력에 %s 가 없음:\n%s", want, out)
+		}
+	}
+}
+
+func TestParseReportsDuplicates(t *testing.T) {
+	in := `<ldi>
+  <element name="A">
+    <uses provider="P" strength="1"/>
+    <property name="p">1</property>
+    <property name="p">2</property>
+  </element>
+  <element name="A">
+    <uses provider="P" strength="2"/>
+    <property name="q">3</property>
+  </element>
+</ldi>`
+	doc, dups, err := parse([]byte(in))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(dups) != 2 || !strings.Contains(dups[0], "property p") || !strings.Contains(dups[1], "uses P") {
+		t.Errorf("중복 보고 = %q", dups)
+	}
+	el := doc.Element("A")
+	if v, _ := el.Property("p"); v != "1" {
+		t.Errorf("p = %q, 첫 값 1 이어야 함", v)
+	}
+	if v, _ := el.Property("q"); v != "3" {
+		t.Errorf("q = %q", v)
+	}
+	if len(el.Uses) != 1 || el.Uses[0].Strength != "1" {
+		t.Errorf("Uses = %+v", el.Uses)
+	}
+}
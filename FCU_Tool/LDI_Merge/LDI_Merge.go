@@ -0,0 +1,204 @@
+package LDI_Merge
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"FCU_Tools/Config"
+	"FCU_Tools/LDI"
+	"FCU_Tools/Public_data"
+)
+
+// Source 는 주 LDI에 병합할 부분 LDI 하나이다 (보통 지표 하나의 계산 결과).
+//   - AddUnmatched : 주 LDI에 없는 요소를 새로 추가할지 여부. false 이면 보고만 하고 버린다.
+type Source struct {
+	Name         string
+	Doc          *LDI.Document
+	AddUnmatched bool
+}
+
+// Conflict 는 주 LDI 요소에 이미 있던 속성과 부분 LDI 속성이 만난 경우 하나이다.
+type Conflict struct {
+	Source   string
+	Element  string
+	Property string
+	Old      string
+	New      string
+	Policy   string
+	Result   string
+}
+
+// SourceReport 는 부분 LDI 하나의 병합 결과이다.
+type SourceReport struct {
+	Name       string
+	Matched    int      // 주 LDI에서 찾은 요소 수
+	Properties int      // 새로 쓴 속성 수 (충돌 해결 결과 포함)
+	Added      []string // 주 LDI에 없어서 새로 추가한 요소
+	Dropped    []string // 주 LDI에 없어서 버린 요소
+	Conflicts  []Conflict
+}
+
+// Report 는 병합 전체의 결과이다.
+type Report struct {
+	Sources []*SourceReport
+}
+
+// Merge 는 sources 를 순서대로 main 에 병합한다 (파일은 건드리지 않는다).
+//
+// 처리 과정:
+//  1. 부분 LDI의 각 요소를 같은 이름의 주 LDI 요소에 대응시킨다.
+//     대응하는 요소가 없으면 AddUnmatched 에 따라 새로 추가하거나 버리고, 두 경우 모두 보고서에 남긴다.
+//  2. uses / tag 는 합집합으로 추가한다.
+//  3. 속성이 주 LDI에 없으면 그대로 추가하고, 이미 있으면 opts.PolicyFor(속성 이름) 정책을 따른다.
+//     - keep      : 기존 값 유지
+//     - overwrite : 부분 LDI 값으로 덮어쓰기
+//     - sum       : 두 값을 숫자로 더하기 (숫자가 아니면 오류)
+//     - error     : 값이 다르면 오류 (같은 값은 충돌로 보지 않는다)
+//
+// 오류가 하나라도 있으면 모든 오류를 모아 돌려주며, 이때 main 은 일부만 병합된 상태일 수 있으므로 쓰지 않아야 한다.
+func Merge(main *LDI.Document, sources []Source, opts Config.MergeOptions) (*Report, error) {
+	report := &Report{}
+	var errs []string
+
+	for _, src := range sources {
+		sr := &SourceReport{Name: src.Name}
+		report.Sources = append(report.Sources, sr)
+
+		for _, el := range src.Doc.Elements() {
+			target := main.Element(el.Name)
+			if target == nil {
+				if !src.AddUnmatched {
+					sr.Dropped = append(sr.Dropped, el.Name)
+					continue
+				}
+				sr.Added = append(sr.Added, el.Name)
+				target = main.AddElement(el.Name)
+			} else {
+				sr.Matched++
+			}
+
+			for _, u := range el.Uses {
+				target.AddUses(u)
+			}
+			for _, t := range el.Tags {
+				target.AddTag(t)
+			}
+
+			for _, p := range el.Properties {
+				old, exists := target.Property(p.Name)
+				if !exists {
+					target.SetProperty(p.Name, p.Value)
+					sr.Properties++
+					continue
+				}
+
+				c := Conflict{Source: src.Name, Element: el.Name, Property: p.Name,
+					Old: old, New: p.Value, Policy: opts.PolicyFor(p.Name), Result: old}
+				switch c.Policy {
+				case Config.ConflictOverwrite:
+					c.Result = p.Value
+				case Config.ConflictSum:
+					sum, err := sumValues(old, p.Value)
+					if err != nil {
+						errs = append(errs, fmt.Sprintf("%s: %s / %s: %v", src.Name, el.Name, p.Name, err))
+						continue
+					}
+					c.Result = sum
+				case Config.ConflictError:
+					if old != p.Value {
+						errs = append(errs, fmt.Sprintf("%s: %s / %s: 기존 값 %s, 새 값 %s", src.Name, el.Name, p.Name, old, p.Value))
+						continue
+					}
+				}
+				if c.Result != old {
+					target.SetProperty(p.Name, c.Result)
+					sr.Properties++
+				}
+				sr.Conflicts = append(sr.Conflicts, c)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return report, fmt.Errorf("속성 충돌을 해결하지 못했습니다:\n  - %s", strings.Join(errs, "\n  - "))
+	}
+	return report, nil
+}
+
+// MergeFile 은 주 LDI 파일(mainPath)을 한 번 읽어 sources 를 모두 병합한 뒤 한 번만 다시 쓴다.
+// 병합 중 오류가 나면 주 LDI 파일은 변경하지 않는다.
+// 쓰기는 같은 폴더의 임시 파일에 먼저 쓰고 교체하므로 중간에 실패해도 원래 파일이 남는다.
+func MergeFile(mainPath string, sources []Source, opts Config.MergeOptions) (*Report, error) {
+	mainDoc, err := LDI.Read(mainPath)
+	if err != nil {
+		return nil, fmt.Errorf("주 LDI 파일 읽기 실패: %v", err)
+	}
+
+	report, err := Merge(mainDoc, sources, opts)
+	if err != nil {
+		report.Print()
+		return report, fmt.Errorf("%v\n(주 LDI는 변경되지 않았습니다)", err)
+	}
+
+	tmpPath := mainPath + ".tmp"
+	if err := mainDoc.Write(tmpPath); err != nil {
+		return report, err
+	}
+	if err := os.Rename(tmpPath, mainPath); err != nil {
+		os.Remove(tmpPath)
+		return report, fmt.Errorf("주 LDI 교체 실패: %v", err)
+	}
+
+	report.Print()
+	return report, nil
+}
+
+// Print 는 부분 LDI별 병합 결과와 대응하지 못한 요소를 모두 출력한다.
+func (r *Report) Print() {
+	for _, sr := range r.Sources {
+		fmt.Printf("✅ %s 지표 병합: 요소 %d개 대응, 속성 %d개 기록\n", sr.Name, sr.Matched, sr.Properties)
+		if len(sr.Added) > 0 {
+			fmt.Printf("ℹ️ %s: 주 LDI에 없는 요소 %d개를 새로 추가했습니다:\n", sr.Name, len(sr.Added))
+			printNames(sr.Added)
+		}
+		if len(sr.Dropped) > 0 {
+			Public_data.Warnf("%s: 주 LDI에 없는 요소 %d개는 병합하지 않았습니다:", sr.Name, len(sr.Dropped))
+			printNames(sr.Dropped)
+		}
+		changed := 0
+		for _, c := range sr.Conflicts {
+			if c.Old != c.New {
+				changed++
+			}
+		}
+		if changed > 0 {
+			fmt.Printf("ℹ️ %s: 이미 값이 다른 속성 %d개를 충돌 정책에 따라 처리했습니다:\n", sr.Name, changed)
+			for _, c := range sr.Conflicts {
+				if c.Old != c.New {
+					fmt.Printf("  - %s / %s: 기존 %s, 새 값 %s → %s (%s)\n", c.Element, c.Property, c.Old, c.New, c.Result, c.Policy)
+				}
+			}
+		}
+	}
+}
+
+func printNames(names []string) {
+	for _, name := range names {
+		fmt.Printf("  - %s\n", name)
+	}
+}
+
+// sumValues 는 두 속성 값을 숫자로 더한다. 결과는 불필요한 0 없이 쓴다 (예: "1" + "2" → "3").
+func sumValues(a, b string) (string, error) {
+	x, err := strconv.ParseFloat(strings.TrimSpace(a), 64)
+	if err != nil {
+		return "", fmt.Errorf("숫자가 아닌 값은 더할 수 없습니다: %q", a)
+	}
+	y, err := strconv.ParseFloat(strings.TrimSpace(b), 64)
+	if err != nil {
+		return "", fmt.Errorf("숫자가 아닌 값은 더할 수 없습니다: %q", b)
+	}
+	return strconv.FormatFloat(x+y, 'f', -1, 64), nil
+}
@@ -0,0 +1,158 @@
+package LDI_Merge
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"FCU_Tools/Config"
+	"FCU_Tools/LDI"
+)
+
+// doc 는 요소 name 하나에 속성 p=value 를 가진 문서를 만든다 (value 가 비면 속성 없음).
+func doc(name, value string) *LDI.Document {
+	d := LDI.New()
+	el := d.AddElement(name)
+	if value != "" {
+		el.SetProperty("p", value)
+	}
+	return d
+}
+
+func TestMergePolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Config.MergeOptions
+		old, new string
+		want     string // 병합 후 주 LDI 값
+		written  int    // SourceReport.Properties
+		err      string // 기대하는 오류 (비면 오류 없음)
+	}{
+		{name: "keep", opts: Config.MergeOptions{Conflict: Config.ConflictKeep}, old: "1", new: "2", want: "1"},
+		{name: "overwrite", opts: Config.MergeOptions{Conflict: Config.ConflictOverwrite}, old: "1", new: "2", want: "2", written: 1},
+		{name: "sum", opts: Config.MergeOptions{Conflict: Config.ConflictSum}, old: "1", new: "2.5", want: "3.5", written: 1},
+		{name: "sum 같은 값", opts: Config.MergeOptions{Conflict: Config.ConflictSum}, old: " 2 ", new: "2", want: "4", written: 1},
+		{name: "sum 기존 값이 숫자가 아님", opts: Config.MergeOptions{Conflict: Config.ConflictSum}, old: "abc", new: "1", want: "abc", err: `숫자가 아닌 값은 더할 수 없습니다: "abc"`},
+		{name: "sum 새 값이 숫자가 아님", opts: Config.MergeOptions{Conflict: Config.ConflictSum}, old: "1", new: "n/a", want: "1", err: `"n/a"`},
+		{name: "error 다른 값", opts: Config.MergeOptions{Conflict: Config.ConflictError}, old: "1", new: "2", want: "1", err: "기존 값 1, 새 값 2"},
+		{name: "error 같은 값", opts: Config.MergeOptions{Conflict: Config.ConflictError}, old: "1", new: "1", want: "1"},
+		{
+			name: "속성별 정책이 우선",
+			opts: Config.MergeOptions{Conflict: Config.ConflictError, Properties: map[string]string{"p": Config.ConflictOverwrite}},
+			old:  "1", new: "2", want: "2", written: 1,
+		},
+		{name: "기존 속성이 없으면 추가", opts: Config.MergeOptions{Conflict: Config.ConflictError}, old: "", new: "2", want: "2", written: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			main := doc("A", tt.old)
+			report, err := Merge(main, []Source{{Name: "M9", Doc: doc("A", tt.new)}}, tt.opts)
+			if tt.err == "" && err != nil {
+				t.Fatalf("Merge: %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("오류 = %v, 기대 %q 포함", err, tt.err)
+			}
+			if got, _ := main.Element("A").Property("p"); got != tt.want {
+				t.Errorf("p = %q, 기대 %q", got, tt.want)
+			}
+			sr := report.Sources[0]
+			if sr.Matched != 1 || sr.Properties != tt.written {
+				t.Errorf("Matched / Properties = %d / %d, 기대 1 / %d", sr.Matched, sr.Properties, tt.written)
+			}
+			// 기존 값이 있던 경우만 충돌로 기록한다 (오류가 난 충돌은 기록하지 않음)
+			wantConflicts := 0
+			if tt.old != "" && tt.err == "" {
+				wantConflicts = 1
+			}
+			if len(sr.Conflicts) != wantConflicts {
+				t.Errorf("충돌 %d 개, 기대 %d 개", len(sr.Conflicts), wantConflicts)
+			}
+		})
+	}
+}
+
+func TestMergeUnmatched(t *testing.T) {
+	for _, add := range []bool{false, true} {
+		main := doc("A", "")
+		src := doc("A", "1")
+		src.Element("A").AddUses(LDI.Uses{Provider: "B", Strength: "2"})
+		src.Element("A").AddTag("t")
+		src.AddElement("B").SetProperty("p", "3")
+		src.AddElement("C")
+
+		report, err := Merge(main, []Source{{Name: "M9", Doc: src, AddUnmatched: add}}, Config.MergeOptions{Conflict: Config.ConflictKeep})
+		if err != nil {
+			t.Fatalf("Merge: %v", err)
+		}
+		sr := report.Sources[0]
+		a := main.Element("A")
+		if len(a.Uses) != 1 || a.Uses[0].Provider != "B" || !reflect.DeepEqual(a.Tags, []string{"t"}) {
+			t.Errorf("uses / tag 가 병합되지 않음: %+v / %v", a.Uses, a.Tags)
+		}
+		if add {
+			if !reflect.DeepEqual(sr.Added, []string{"B", "C"}) || sr.Dropped != nil {
+				t.Errorf("Added / Dropped = %v / %v", sr.Added, sr.Dropped)
+			}
+			if v, _ := main.Element("B").Property("p"); v != "3" {
+				t.Errorf("추가한 요소 B 의 p = %q", v)
+			}
+		} else {
+			if !reflect.DeepEqual(sr.Dropped, []string{"B", "C"}) || sr.Added != nil {
+				t.Errorf("Added / Dropped = %v / %v", sr.Added, sr.Dropped)
+			}
+			if main.Element("B") != nil {
+				t.Error("AddUnmatched=false 인데 요소 B 가 추가됨")
+			}
+		}
+		if sr.Matched != 1 {
+			t.Errorf("Matched = %d", sr.Matched)
+		}
+	}
+}
+
+func TestMergeFile(t *testing.T) {
+	// 정렬·들여쓰기가 LDI.Bytes 와 달라서, 다시 쓰면 내용이 바뀐다
+	original := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<ldi>
+    <element name="B"><property name="p">1</property></element>
+    <element name="A"><property name="p">1</property></element>
+</ldi>
+`)
+	sources := func() []Source {
+		// 첫 지표는 문제없이 병합되고, 두 번째 지표에서 충돌이 난다
+		return []Source{{Name: "M8", Doc: doc("A", "1")}, {Name: "M9", Doc: doc("B", "2")}}
+	}
+	mainPath := filepath.Join(t.TempDir(), "result.ldi.xml")
+	if err := os.WriteFile(mainPath, original, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := MergeFile(mainPath, sources(), Config.MergeOptions{Conflict: Config.ConflictError}); err == nil {
+		t.Fatal("error 정책에서 충돌이 났는데 오류가 없음")
+	}
+	got, err := os.ReadFile(mainPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, original) {
+		t.Errorf("오류가 났는데 주 LDI가 바뀜:\n%s", got)
+	}
+	if _, err := os.Stat(mainPath + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("임시 파일이 남음: %v", err)
+	}
+
+	if _, err := MergeFile(mainPath, sources(), Config.MergeOptions{Conflict: Config.ConflictOverwrite}); err != nil {
+		t.Fatalf("MergeFile: %v", err)
+	}
+	merged, err := LDI.Read(mainPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := merged.Element("B").Property("p"); v != "2" {
+		t.Errorf("overwrite 후 B/p = %q, 기대 2", v)
+	}
+}
@@ -1,90 +1,547 @@
 package Analysis_Process
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"maps"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 
+	"FCU_Tools/Config"
+	"FCU_Tools/M1/C_S_Analysis"
+	"FCU_Tools/M1/Connection_Analysis"
+	"FCU_Tools/M1/Interface_Analysis"
 	"FCU_Tools/M1/M1_Public_Data"
+	"FCU_Tools/M1/Model_IR"
+	"FCU_Tools/M1/Stateflow_Analysis"
 	"FCU_Tools/M1/System_Analysis"
+	"FCU_Tools/M1/System_Tree"
+	"FCU_Tools/M1/Variant_Analysis"
+	"FCU_Tools/Public_data"
+	"FCU_Tools/Run_Manifest"
 )
 
-// 第一层固定：只分析 BuildDir/<Model>/simulink/systems/system_root.xml
-func RunAnalysis(maxDepth int) {
+// 单个模型的分析结果，由 worker 填写，全部完成后按 slxPaths 顺序统一输出
+type modelResult struct {
+	name    string
+	depth   Config.M1Depth
+	skipped bool            // 没有 system_root.xml
+	ir      *Model_IR.Model // 分析结果（中间表示）
+	openErr error           // 读取 slx 失败（包括被拒绝的不安全 slx）
+	err     error           // 解析失败
 
-	buildRoot := M1_Public_Data.BuildDir
-	if buildRoot == "" {
-		fmt.Println("❌ BuildDir 为空，请先调用 SetWorkDir() 初始化工作空间")
+	warnings []string       // ModelReference 找不到 / 循环引用，库链接无法展开，根层接口解析失败，无法确定激活的 variant，根层接口不一致
+	noLib    map[string]int // 不在 inputs.library_dirs 中的库 → 链接数（如 Simulink 自带库，只提示）
+	variants []Run_Manifest.VariantChoice
+}
+
+// 第一层固定：只分析每个 slx 内的 simulink/systems/system_root.xml
+// 分析深度按模型取 metrics.m1.model_depth（没有则 metrics.m1.depth），auto 表示一直到没有下一层 system 为止；
+// 每层按 metrics.m1.levels 的规则选择 Block
+// slxPaths 为 File_Utils_M1.FindSlxModels 找到的模型文件，直接从 zip 读取，不依赖 BuildDir
+// libPaths 为 File_Utils_M1.FindLibraries 找到的库文件，库链接（Reference）按库中来源 Block 展开，来自库的 Block 在中间表示中标出
+//
+// 每个模型解析成系统树（System_Tree），由最多 ws.Jobs 个 worker 并行分析；分析完的模型即从缓存释放，
+// 只有被 ModelReference 引用的模型和库一直缓存（只解析一次），内存不随输入模型数增长；
+// ModelReference Block 到输入目录中同名的 slx 继续展开（metrics.m1.follow_model_refs），引用链记录在 Model_IR.Reference；
+// Variant Subsystem 按 metrics.m1.variants.mode 只保留计入的 choice，选择结果记录在 Model_IR.Variant 和 run.json；
+// 根层接口（graphicalInterface.xml）每个模型只解析一次：C-S 端口计入 L1，其余只记录在 Model_IR.Interface（不计入 M1），
+// 与 system_root.xml 不一致的地方给出警告；
+// 结果先保存在各自的 modelResult，全部完成后按 slxPaths 顺序写出、打印信息，所以输出与调度顺序无关。
+//   - <IRDir>/<Model>.json : 中间表示（Model_IR），M1 计算使用
+//   - <TxtDir>/<Model>.txt : 仅在 metrics.m1.write_txt 打开时写出，供人工查看
+//
+// 返回成功分析的模型（按 slxPaths 顺序）。读取失败（包括被拒绝的不安全 slx）的模型跳过，
+// 其余模型照常分析，最后汇总返回这些错误
+func RunAnalysis(ws *M1_Public_Data.Workspace, slxPaths, libPaths []string) ([]*Model_IR.Model, error) {
+	results := make([]*modelResult, len(slxPaths))
+	for i := range results {
+		results[i] = &modelResult{}
+	}
+
+	jobs := ws.Jobs
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	if jobs > len(slxPaths) {
+		jobs = len(slxPaths)
+	}
+
+	// 所有模型共用一个模型库，ModelReference 引用的模型、库链接的库从这里取
+	lib := System_Tree.NewLibrary(slxPaths, libPaths, ws.Limits)
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for j := 0; j < jobs; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				analyzeModel(ws, lib, slxPaths[i], results[i])
+			}
+		}()
+	}
+	for i := range slxPaths {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	var models []*Model_IR.Model
+	var errs []error
+	for _, r := range results {
+		if r.openErr != nil {
+			errs = append(errs, r.openErr)
+			continue
+		}
+		if r.skipped {
+			continue // 模型没有 system_root.xml，跳过
+		}
+
+		fmt.Printf("🔍 分析模型 [%s] (最大深度: %s)\n", r.name, r.depth)
+		if r.err != nil {
+			fmt.Println("❌ 分析失败：", r.err)
+			continue
+		}
+		for _, w := range r.warnings {
+			Public_data.Warnf("%s", w)
+		}
+		for _, name := range slices.Sorted(maps.Keys(r.noLib)) {
+			fmt.Printf("ℹ️ 模型 [%s] 中 %d 个库链接来自库 %s，该库不在 inputs.library_dirs 中，未展开\n", r.name, r.noLib[name], name)
+		}
+		if len(r.ir.Models) > 1 {
+			fmt.Printf("ℹ️ 模型 [%s] 由 %s 组成\n", r.name, strings.Join(r.ir.Models, ", "))
+		}
+		Run_Manifest.RecordVariants(r.variants...)
+
+		if err := r.ir.WriteJSON(filepath.Join(ws.IRDir, r.name+".json")); err != nil {
+			fmt.Println("❌", err)
+		}
+		if ws.WriteTxt {
+			if err := r.ir.WriteTxt(filepath.Join(ws.TxtDir, r.name+".txt")); err != nil {
+				fmt.Println("❌", err)
+			}
+		}
+		models = append(models, r.ir)
+	}
+
+	fmt.Println("✅ 分析完成")
+	return models, errors.Join(errs...)
+}
+
+// analyzeModel 读取并解析一个 slx，把分析结果写进 r（只由一个 worker 调用）
+// slx 与系统树都从 lib 中取，分析完后释放；被其他模型引用的模型留在缓存中，只解析一次
+func analyzeModel(ws *M1_Public_Data.Workspace, lib *System_Tree.Library, slxPath string, r *modelResult) {
+	// 分析完后释放（被其他模型引用的仍保留在缓存中）
+	defer lib.Release(slxPath)
+
+	slx, err := lib.Open(slxPath)
+	if err != nil {
+		r.openErr = err
+		return
+	}
+	r.name = slx.Name
+	r.depth = ws.Options.DepthFor(slx.Name)
+
+	// 固定结构：<slx>/simulink/systems/system_root.xml
+	model, err := lib.Tree(slxPath)
+	if err != nil {
+		r.err = err
+		return
+	}
+	if model == nil {
+		r.skipped = true
 		return
 	}
 
-	// BuildDir 下的模型目录
-	modelDirs, err := os.ReadDir(buildRoot)
+	// 启动递归分析，从第1层开始，L1 没有父节点
+	r.ir = &Model_IR.Model{Name: model.Name, Models: []string{model.Name}}
+	a := &analyzer{
+		opts: ws.Options,
+		conn: Connection_Analysis.Options{
+			Kinds:    ws.Options.ConnectionKinds,
+			Variants: Variant_Analysis.NewSelector(ws.Options.Variants),
+		},
+		depth:   r.depth,
+		lib:     lib,
+		ir:      r.ir,
+		chain:   []string{model.Name},
+		path:    []string{model.Name},
+		onPath:  make(map[*System_Tree.System]bool),
+		noLib:   make(map[string]int),
+		checked: make(map[*System_Tree.Block]bool),
+		charts:  make(map[*System_Tree.Model][]*Stateflow_Analysis.Chart),
+	}
+	a.analyzeRecursive(nil, model, model.Root, 1)
+	a.analyzeInterface(model)
+	r.warnings, r.noLib, r.variants = a.warnings, a.noLib, a.variants
+}
+
+// analyzeInterface 解析模型根层接口（slx 内的 simulink/graphicalInterface.xml，每个模型只解析一次）
+//   - C-S 端口（RequireFunction / ProvideFunction）追加到 L1：原 txt 中 C-S 端口写在 L1 最后，被计入最后一个 L1 Block，这里保持相同归属
+//   - 根层 S-R 端口、总线对象、function-call 输入、Data Store 只写进中间表示（Model_IR.Interface）并与 system_root.xml 对照，
+//     不计入 M1：根层 Inport / Outport 已经作为 L1 Block 的真实端口从 system_root.xml 计入
+//
+// 读取 / 解析失败不打断分析，只记录一次警告；不一致的地方也记录为警告（与其他警告一起按模型顺序输出）
+func (a *analyzer) analyzeInterface(model *System_Tree.Model) {
+	iface, err := Interface_Analysis.GetInterface(model.SLX.FS)
 	if err != nil {
-		fmt.Println("❌ 无法读取 BuildDir 目录：", err)
+		a.warnf("模型 [%s] 解析根层接口（C-S 端口）失败：%v", model.Name, err)
 		return
 	}
 
-	for _, modelEntry := range modelDirs {
-		if !modelEntry.IsDir() {
-			continue
+	if len(a.ir.Blocks) > 0 {
+		last := a.ir.Blocks[len(a.ir.Blocks)-1]
+		for _, p := range C_S_Analysis.GetCSPorts(iface) {
+			last.Ports = append(last.Ports, Model_IR.Port{
+				Name:      p.Name,
+				SID:       p.SID,
+				BlockType: p.BlockType,
+				PortType:  p.PortType,
+			})
 		}
+	}
 
-		modelName := modelEntry.Name()
-		modelPath := filepath.Join(buildRoot, modelName)
+	a.ir.Interface = &Model_IR.Interface{
+		BusObjects:    iface.BusObjects(),
+		FunctionCalls: iface.FunctionCallInputs(),
+		Provides:      iface.Provides,
+		Requires:      iface.Requires,
+		DataStores:    iface.DataStores,
+		Discrepancies: Interface_Analysis.CrossCheck(iface, model),
+	}
+	for _, p := range iface.Inports {
+		a.ir.Interface.Inports = append(a.ir.Interface.Inports, Model_IR.RootPort(p))
+	}
+	for _, p := range iface.Outports {
+		a.ir.Interface.Outports = append(a.ir.Interface.Outports, Model_IR.RootPort(p))
+	}
+	for _, d := range a.ir.Interface.Discrepancies {
+		a.warnf("模型 [%s] 根层接口不一致：%s", model.Name, d)
+	}
+}
 
-		// 固定结构：<BuildDir>/<Model>/simulink/systems/system_root.xml
-		sysDir := filepath.Join(modelPath, "simulink", "systems")
-		xmlPath := filepath.Join(sysDir, "system_root.xml")
+// analyzer 保存一个模型递归分析时的参数和状态
+type analyzer struct {
+	opts     Config.M1Options
+	conn     Connection_Analysis.Options
+	depth    Config.M1Depth
+	lib      *System_Tree.Library
+	ir       *Model_IR.Model
+	chain    []string                     // 当前递归路径上的模型引用链（被分析模型 → 被引用模型 → ...）
+	onPath   map[*System_Tree.System]bool // 当前递归路径上的 system，防止异常模型中的循环引用
+	warnings []string                     // 找不到的引用、循环引用、无法展开的库链接，全部分析完后按模型顺序输出
+	noLib    map[string]int               // 找不到的库 → 链接数
+	checked  map[*System_Tree.Block]bool  // 已检查过的库链接、Variant Source / Sink
+	path     []string                     // 当前 system 的 Simulink 路径（模型名/Block/...），用来找 Stateflow chart
+	charts   map[*System_Tree.Model][]*Stateflow_Analysis.Chart
+	variants []Run_Manifest.VariantChoice // 各 Variant Subsystem 的选择结果
+}
 
-		if _, err := os.Stat(xmlPath); err != nil {
-			continue // 模型没有 system_root.xml，跳过
+// 递归分析函数，根据分析深度控制递归深度
+// parent：上一层 Block 的中间表示，本层 Block 挂到 parent.Children（L1 为 nil，挂到 ir.Blocks）
+// model：sys 所在的模型（展开 ModelReference 后为被引用的模型，展开库链接后为库）
+func (a *analyzer) analyzeRecursive(parent *Model_IR.Block, model *System_Tree.Model, sys *System_Tree.System, currentLevel int) {
+	// 如果当前层数超过最大深度，停止递归
+	if !a.depth.Reaches(currentLevel) || a.onPath[sys] {
+		return
+	}
+	a.onPath[sys] = true
+	defer delete(a.onPath, sys)
+	a.checkLinks(sys)
+	a.checkVariantPorts(sys)
+
+	// 统一入口，由 System_Analysis 按本层规则筛选
+	subsystems := System_Analysis.AnalyzeSubSystems(model, sys, currentLevel, a.opts.LevelRule(currentLevel), a.lib, a.conn)
+	// 库链接展开后的 Block 都来自库
+	inLibrary := parent != nil && (parent.InLibrary || parent.Library != nil && parent.Library.Status == Model_IR.RefResolved)
+	for _, sub := range subsystems {
+		sub.Node.InLibrary = inLibrary
+		if sub.Link != nil {
+			status := Model_IR.RefResolved
+			if sub.Link.Err != nil {
+				status = Model_IR.RefMissing
+			}
+			sub.Node.Library = &Model_IR.LibraryLink{SourceBlock: sub.Link.SourceBlock, Status: status}
 		}
+		if sub.RefModel != "" {
+			sub.Node.Reference = &Model_IR.Reference{
+				Model:  sub.RefModel,
+				Chain:  append(append([]string(nil), a.chain...), sub.RefModel),
+				Status: Model_IR.RefSkipped,
+			}
+		}
+		if parent == nil {
+			a.ir.Blocks = append(a.ir.Blocks, sub.Node)
+		} else {
+			parent.Children = append(parent.Children, sub.Node)
+		}
+	}
 
-		fmt.Printf("🔍 分析模型 [%s] (最大深度: %d)\n", modelName, maxDepth)
+	// Stateflow chart 的结构统计与深度无关：最深一层的 chart 同样记录（sub.Node.Chart）
+	charts := make([]*Stateflow_Analysis.Chart, len(subsystems))
+	for i, sub := range subsystems {
+		if sub.SFBlockType != "" {
+			charts[i] = a.analyzeChart(model, sub)
+		}
+	}
 
-		// 启动递归分析，从第1层开始，L1 没有父节点
-		err = analyzeRecursive(sysDir, "system_root.xml", 1, maxDepth, "")
-		if err != nil {
-			fmt.Println("❌ 分析失败：", err)
-			continue
+	// 递归分析下一层
+	nextLevel := currentLevel + 1
+	if len(subsystems) > 0 && a.depth.Reaches(nextLevel) {
+		for i, sub := range subsystems {
+			if sub.RefModel != "" && sub.Child == nil {
+				if a.opts.FollowModelRefs {
+					a.followReference(sub, nextLevel)
+				}
+				continue
+			}
+			// Stateflow chart：下一层为 chart 逻辑节点，不分析 chart 内部自动生成的 Block
+			if charts[i] != nil {
+				addChartLevel(sub, charts[i], nextLevel)
+				continue
+			}
+			// 没有 system_<SID>.xml 的 Block 没有下一层
+			if sub.Child == nil {
+				continue
+			}
+			// 库链接的内容在库的系统树中，路径从库中的来源 Block 开始
+			childModel := model
+			path := a.path
+			if sub.Link != nil && sub.Link.Err == nil {
+				childModel = sub.Link.Model
+				a.path = strings.Split(sub.Link.SourceBlock, "/")
+			} else {
+				a.path = append(slices.Clip(a.path), sub.Name)
+			}
+			a.analyzeRecursive(sub.Node, childModel, sub.Child, nextLevel)
+			a.path = path
+			if Variant_Analysis.IsVariantSubsystem(sub.Block) {
+				a.selectVariant(sub)
+			}
 		}
 	}
+}
 
-	fmt.Printf("✅ 分析完成 (最大深度: %d)\n", maxDepth)
+// followReference 在输入目录中找到 ModelReference 引用的模型，把它 system_root.xml 中的 Block 作为下一层继续分析
+// 找不到、读取失败或形成循环引用时只记录警告，不展开
+func (a *analyzer) followReference(sub System_Analysis.SubSystemInfo, nextLevel int) {
+	ref := sub.Node.Reference
+	where := strings.Join(a.chain, " → ") + " / " + sub.Name
+
+	slxPath, ok := a.lib.Lookup(sub.RefModel)
+	if !ok {
+		ref.Status = Model_IR.RefMissing
+		a.warnf("ModelReference [%s] 引用的模型 %s 不在输入目录中", where, sub.RefModel)
+		return
+	}
+
+	target, err := a.lib.RefTree(slxPath)
+	if err == nil && target == nil {
+		err = fmt.Errorf("没有 %s", System_Tree.RootFile)
+	}
+	if err != nil {
+		ref.Status = Model_IR.RefMissing
+		a.warnf("ModelReference [%s] 引用的模型 %s 无法读取：%v", where, sub.RefModel, err)
+		return
+	}
+
+	// 引用链上使用实际的模型名（输入的引用名可能大小写不同）
+	ref.Model = target.Name
+	ref.Chain[len(ref.Chain)-1] = target.Name
+	if slices.Contains(a.chain, target.Name) {
+		ref.Status = Model_IR.RefCycle
+		a.warnf("ModelReference [%s] 形成循环引用：%s", where, strings.Join(ref.Chain, " → "))
+		return
+	}
+
+	ref.Status = Model_IR.RefResolved
+	if !slices.Contains(a.ir.Models, target.Name) {
+		a.ir.Models = append(a.ir.Models, target.Name)
+	}
+	a.chain = append(a.chain, target.Name)
+	path := a.path
+	a.path = []string{target.Name}
+	a.analyzeRecursive(sub.Node, target, target.Root, nextLevel)
+	a.path = path
+	a.chain = a.chain[:len(a.chain)-1]
 }
 
-// 递归分析函数，根据 maxDepth 控制递归深度
-// fatherName：当前这一层 System 对应的“父节点名称”，用于下一层输出 FatherNode 信息
-func analyzeRecursive(dir, file string, currentLevel, maxDepth int, fatherName string) error {
-	// 如果当前层数超过最大深度，停止递归
-	if currentLevel > maxDepth {
+// analyzeChart 在模型的 simulink/stateflow/*.xml 中找到 sub 对应的 chart，把结构统计记到 sub.Node.Chart 并返回该 chart
+// 找不到 chart 时警告并返回 nil，由调用方按普通 SubSystem 继续分析
+func (a *analyzer) analyzeChart(model *System_Tree.Model, sub System_Analysis.SubSystemInfo) *Stateflow_Analysis.Chart {
+	charts, ok := a.charts[model]
+	if !ok {
+		var err error
+		if charts, err = Stateflow_Analysis.GetCharts(model.SLX.FS); err != nil {
+			a.warnf("解析 Stateflow 失败 [%s]：%v", model.Name, err)
+		}
+		a.charts[model] = charts
+	}
+
+	blockPath := strings.Join(append(slices.Clip(a.path), sub.Name), "/")
+	c := Stateflow_Analysis.FindChart(charts, blockPath, sub.Name)
+	if c == nil {
+		a.warnf("Stateflow Block [%s]（%s）找不到对应的 chart，按普通 SubSystem 分析", blockPath, sub.SFBlockType)
 		return nil
 	}
 
-	// 统一入口，由 System_Analysis 按 level 决定筛选逻辑
-	subsystems, err := System_Analysis.AnalyzeSubSystemsInFile(dir, file, currentLevel, fatherName)
-	if err != nil {
-		return err
+	sub.Node.Chart = &Model_IR.Chart{
+		States:      c.States,
+		Transitions: c.Transitions,
+		Depth:       c.Depth,
+		Inputs:      itemNames(c.Inputs),
+		Outputs:     itemNames(c.Outputs),
+		Locals:      itemNames(c.Locals),
+		Events:      itemNames(c.Events),
+		Functions:   c.Functions,
 	}
+	return c
+}
 
-	// 递归分析下一层
-	if len(subsystems) > 0 && currentLevel < maxDepth {
-		nextLevel := currentLevel + 1
-		for _, sub := range subsystems {
-			nextFile := fmt.Sprintf("system_%s.xml", sub.SID)
-			nextFull := filepath.Join(dir, nextFile)
-
-			if _, err := os.Stat(nextFull); err == nil {
-				// 下一层的父节点 = 当前这一层的子系统名称
-				nextFather := strings.TrimSpace(sub.Name)
-				if err := analyzeRecursive(dir, nextFile, nextLevel, maxDepth, nextFather); err != nil {
-					return err
-				}
+// addChartLevel 在 chart 的下一层挂一个 BlockStateflow 节点，端口为 chart 的输入输出（data 和 event）
+func addChartLevel(sub System_Analysis.SubSystemInfo, c *Stateflow_Analysis.Chart, nextLevel int) {
+	logic := &Model_IR.Block{
+		Level:     nextLevel,
+		Name:      sub.Name,
+		SID:       sub.SID,
+		BlockType: Model_IR.BlockStateflow,
+		InLibrary: sub.Node.InLibrary || sub.Node.Library != nil && sub.Node.Library.Status == Model_IR.RefResolved,
+	}
+	// 输入 event 即 chart 的 trigger 端口
+	for _, it := range c.Inputs {
+		p := Model_IR.Port{Name: it.Name, SID: "sf:" + it.SSID, BlockType: "Inport", PortType: Model_IR.PortSR}
+		if it.Event {
+			p.Control = Model_IR.ControlTrigger
+		}
+		logic.Ports = append(logic.Ports, p)
+	}
+	for _, it := range c.Outputs {
+		logic.Ports = append(logic.Ports, Model_IR.Port{Name: it.Name, SID: "sf:" + it.SSID, BlockType: "Outport", PortType: Model_IR.PortSR})
+	}
+
+	sub.Node.Children = append(sub.Node.Children, logic)
+}
+
+func itemNames(items []Stateflow_Analysis.Item) []string {
+	var names []string
+	for _, it := range items {
+		names = append(names, it.Name)
+	}
+	return names
+}
+
+// selectVariant 按 metrics.m1.variants.mode 在 Variant Subsystem 的下一层只保留计入的 choice
+//   - all        : 全部保留
+//   - active     : 只保留激活的 choice（按 metrics.m1.variants.controls 计算），无法确定时警告并全部保留
+//   - worst_case : 只保留整棵子树端口数最多的 choice
+func (a *analyzer) selectVariant(sub System_Analysis.SubSystemInfo) {
+	where := strings.Join(append(slices.Clip(a.path), sub.Name), "/")
+
+	choices := make(map[string]string) // SID → 名字
+	var names []string
+	for _, c := range Variant_Analysis.Choices(sub.Block) {
+		name := strings.Join(strings.Fields(c.Name), " ")
+		choices[c.SID] = name
+		names = append(names, name)
+	}
+
+	keep := ""
+	switch a.conn.Variants.Mode {
+	case Config.VariantActive:
+		c, err := a.conn.Variants.ActiveChoice(sub.Block)
+		if err != nil {
+			a.warnf("Variant Subsystem [%s] 无法确定激活的 choice，全部计入：%v", where, err)
+			break
+		}
+		keep = c.SID
+	case Config.VariantWorstCase:
+		most := -1
+		for _, child := range sub.Node.Children {
+			if _, ok := choices[child.SID]; !ok {
+				continue
+			}
+			if n := subtreePorts(child); n > most {
+				keep, most = child.SID, n
 			}
 		}
 	}
 
-	return nil
+	chosen := names
+	if keep != "" {
+		chosen = []string{choices[keep]}
+		var children []*Model_IR.Block
+		for _, child := range sub.Node.Children {
+			if _, ok := choices[child.SID]; !ok || child.SID == keep {
+				children = append(children, child)
+			}
+		}
+		sub.Node.Children = children
+	}
+
+	sub.Node.Variant = &Model_IR.Variant{Mode: a.conn.Variants.Mode, Choices: names, Chosen: chosen}
+	a.variants = append(a.variants, Run_Manifest.VariantChoice{
+		Model:   a.ir.Name,
+		Block:   where,
+		Mode:    a.conn.Variants.Mode,
+		Choices: names,
+		Chosen:  chosen,
+	})
+}
+
+// subtreePorts 返回 b 及其所有下层 Block 的端口数之和
+func subtreePorts(b *Model_IR.Block) int {
+	n := len(b.Ports)
+	for _, c := range b.Children {
+		n += subtreePorts(c)
+	}
+	return n
+}
+
+// checkVariantPorts 在 metrics.m1.variants.mode=active 时检查 sys 中的 Variant Source / Sink，
+// 无法确定激活端口的警告（这些 Block 的连线全部计入）；同一个 Block 只检查一次
+func (a *analyzer) checkVariantPorts(sys *System_Tree.System) {
+	if a.conn.Variants.Mode != Config.VariantActive {
+		return
+	}
+	for _, b := range sys.Blocks {
+		if !Variant_Analysis.IsVariantPort(b) || a.checked[b] {
+			continue
+		}
+		a.checked[b] = true
+		if _, err := a.conn.Variants.ActivePort(b); err != nil {
+			where := strings.Join(append(slices.Clip(a.path), strings.Join(strings.Fields(b.Name), " ")), "/")
+			a.warnf("%s [%s] 无法确定激活的端口，全部计入：%v", b.BlockType, where, err)
+		}
+	}
+}
+
+// checkLinks 检查 sys 中所有库链接（不论本层是否选中）：找不到库只计数（Simulink 自带库等），
+// 库中找不到来源 Block 或库无法读取时警告；同一个 Block 只检查一次
+func (a *analyzer) checkLinks(sys *System_Tree.System) {
+	for _, b := range sys.Blocks {
+		src := b.SourceBlock()
+		if src == "" || a.checked[b] {
+			continue
+		}
+		a.checked[b] = true
+
+		link := a.lib.ResolveLink(src)
+		if link.Err == nil {
+			continue
+		}
+		if errors.Is(link.Err, System_Tree.ErrLibraryNotFound) {
+			a.noLib[link.Library]++
+			continue
+		}
+		where := strings.Join(a.chain, " → ") + " / " + strings.Join(strings.Fields(b.Name), " ")
+		a.warnf("库链接 [%s] 无法展开 %q：%v", where, src, link.Err)
+	}
+}
+
+func (a *analyzer) warnf(format string, args ...interface{}) {
+	a.warnings = append(a.warnings, fmt.Sprintf(format, args...))
 }
@@ -1,13 +1,7 @@
 package C_S_Analysis
 
 import (
-	"encoding/xml"
-	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
-
-	"FCU_Tools/M1/M1_Public_Data"
+	"FCU_Tools/M1/Interface_Analysis"
 )
 
 // 对外暴露的 C-S 端口信息
@@ -18,59 +12,17 @@ type CSPort struct {
 	PortType  string // 固定 "C-S"
 }
 
-// 内部 XML 结构
-type xmlP struct {
-	Name  string `xml:"Name,attr"`
-	Value string `xml:",chardata"`
-}
-
-type xmlRequireFunction struct {
-	Ps []xmlP `xml:"P"`
-}
-
-type xmlProvideFunction struct {
-	Ps []xmlP `xml:"P"`
-}
-
-type xmlGraphicalInterface struct {
-	Requires []xmlRequireFunction `xml:"RequireFunction"`
-	Provides []xmlProvideFunction `xml:"ProvideFunction"`
-}
-
-// 从 BuildDir\<modelName>\simulink\graphicalInterface.xml 中解析 C-S 端口
-func GetCSPorts(modelName string) ([]CSPort, error) {
+// 从模型根层接口（Interface_Analysis.GetInterface 解析的 simulink/graphicalInterface.xml）中取出 C-S 端口
+// 调用方只解析一次 graphicalInterface.xml，读取失败由调用方提示
+func GetCSPorts(iface *Interface_Analysis.Interface) []CSPort {
 	var result []CSPort
 
-	if M1_Public_Data.BuildDir == "" || modelName == "" {
-		return result, nil
-	}
-
-	// BuildDir\<Model>\simulink\graphicalInterface.xml
-	giPath := filepath.Join(M1_Public_Data.BuildDir, modelName, "simulink", "graphicalInterface.xml")
-
-	data, err := os.ReadFile(giPath)
-	if err != nil {
-		// 如果文件不存在或读取失败，这里返回空列表但带错误信息，由调用方决定是否打印告警
-		return result, fmt.Errorf("读取 graphicalInterface.xml 失败 [%s]: %w", giPath, err)
-	}
-
-	var gi xmlGraphicalInterface
-	if err := xml.Unmarshal(data, &gi); err != nil {
-		return result, fmt.Errorf("解析 graphicalInterface.xml 失败 [%s]: %w", giPath, err)
+	if iface == nil {
+		return result
 	}
 
 	// RequireFunction → 视为 Inport
-	for _, rf := range gi.Requires {
-		name := ""
-		for _, p := range rf.Ps {
-			if p.Name == "Name" {
-				name = normalizeName(p.Value)
-				break
-			}
-		}
-		if name == "" {
-			continue
-		}
+	for _, name := range iface.Requires {
 		result = append(result, CSPort{
 			Name:      name,
 			BlockType: "Inport",
@@ -80,17 +32,7 @@ func GetCSPorts(modelName string) ([]CSPort, error) {
 	}
 
 	// ProvideFunction → 视为 Outport
-	for _, pf := range gi.Provides {
-		name := ""
-		for _, p := range pf.Ps {
-			if p.Name == "Name" {
-				name = normalizeName(p.Value)
-				break
-			}
-		}
-		if name == "" {
-			continue
-		}
+	for _, name := range iface.Provides {
 		result = append(result, CSPort{
 			Name:      name,
 			BlockType: "Outport",
@@ -99,14 +41,5 @@ func GetCSPorts(modelName string) ([]CSPort, error) {
 		})
 	}
 
-	return result, nil
-}
-
-// 把名字里的换行 / 多余空白压成一个空格
-func normalizeName(s string) string {
-	s = strings.TrimSpace(s)
-	if s == "" {
-		return s
-	}
-	return strings.Join(strings.Fields(s), " ")
+	return result
 }
@@ -1,79 +1,124 @@
 package Connection_Analysis
 
 import (
-	"encoding/xml"
 	"fmt"
-	"os"
-	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+
+	"FCU_Tools/Config"
+	"FCU_Tools/M1/System_Tree"
+	"FCU_Tools/M1/Variant_Analysis"
 )
 
-// P 标签
-type xmlP struct {
-	Name  string `xml:"Name,attr"`
-	Value string `xml:",chardata"`
-}
+// 连接类型（metrics.m1.connection_kinds）
+const (
+	KindLine       = "line"        // <Line> 的 Src → Dst
+	KindGoto       = "goto"        // Goto → From（按 GotoTag 和可见范围）
+	KindDataStore  = "datastore"   // Data Store Write → Data Store Read（按 DataStoreName 和 Data Store Memory 的范围）
+	KindBusElement = "bus_element" // Bus Creator 的输入 → Bus Selector 选出的同名信号
+)
 
-// Branch 标签
-type xmlBranch struct {
-	Ps []xmlP `xml:"P"`
+// Options 是连接分析的参数
+//   - Kinds    : 计入的连接类型（metrics.m1.connection_kinds）
+//   - Variants : metrics.m1.variants；mode 为 active 时 Variant Source / Sink 只保留激活端口上的连线
+type Options struct {
+	Kinds    []string
+	Variants Variant_Analysis.Selector
 }
 
-// Line 标签
-type xmlLine struct {
-	Ps       []xmlP     `xml:"P"`
-	Branches []xmlBranch `xml:"Branch"`
+// 端点的端口类型（SLX 中 "#" 后的部分）
+const (
+	PortIn       = "in"
+	PortOut      = "out"
+	PortTrigger  = "trigger"
+	PortEnable   = "enable"
+	PortIfAction = "ifaction"
+	PortState    = "state"
+)
+
+// Endpoint 是连线的一端："66#in:3" → {SID: "66", Port: "in", Index: 3}
+// trigger / enable 等没有序号的端口 Index 为 0；隐式连接的端点只有 SID
+type Endpoint struct {
+	SID   string
+	Port  string
+	Index int
 }
 
-// 只关心 Line 的 System
-type xmlSystem struct {
-	Lines []xmlLine `xml:"Line"`
+// String 还原成 SLX 中的写法，如 "66#in:3" / "202#trigger" / "66"
+func (e Endpoint) String() string {
+	switch {
+	case e.Port == "":
+		return e.SID
+	case e.Index == 0:
+		return e.SID + "#" + e.Port
+	default:
+		return fmt.Sprintf("%s#%s:%d", e.SID, e.Port, e.Index)
+	}
 }
 
-// 一条连接边：SrcSID → DstSID
+// 一条连接边：Src → Dst
+//   - Kind : 连接类型（KindXxx）
+//   - Name : 隐式连接的名字（GotoTag / DataStoreName / 总线信号名），KindLine 为空
 type Edge struct {
-	SrcSID string
-	DstSID string
+	Src  Endpoint
+	Dst  Endpoint
+	Kind string
+	Name string
 }
 
-// 解析某个 system_xxx.xml 中的所有连接，返回 Edge 列表
-func AnalyzeConnectionsInFile(dir, file string) ([]Edge, error) {
-	fullPath := filepath.Join(dir, file)
+// 从已解析的 system 中取出 opts.Kinds 中各类连接，返回 Edge 列表（先 <Line>，再按 kinds 顺序追加隐式连接）
+//
+// 隐式连接的两端是 sys 中的 Block：Goto/From、Data Store Read/Write 在 sys 本层时就是它们自己，
+// 在某个 SubSystem 内部（任意深度）时归到 sys 中的这个 SubSystem；两端归到同一个 Block 的连接在本层不可见，不输出
+func AnalyzeConnections(sys *System_Tree.System, opts Options) []Edge {
+	var edges []Edge
+	kinds := opts.Kinds
 
-	data, err := os.ReadFile(fullPath)
-	if err != nil {
-		return nil, fmt.Errorf("读取 XML 失败 [%s]: %w", fullPath, err)
+	if slices.Contains(kinds, KindLine) {
+		edges = append(edges, lineEdges(sys)...)
+		if opts.Variants.Mode == Config.VariantActive {
+			edges = dropInactive(sys, edges, opts.Variants)
+		}
 	}
-
-	var sys xmlSystem
-	if err := xml.Unmarshal(data, &sys); err != nil {
-		return nil, fmt.Errorf("解析 XML 失败 [%s]: %w", fullPath, err)
+	if slices.Contains(kinds, KindGoto) {
+		edges = append(edges, gotoEdges(sys)...)
+	}
+	if slices.Contains(kinds, KindDataStore) {
+		edges = append(edges, dataStoreEdges(sys)...)
 	}
+	if slices.Contains(kinds, KindBusElement) {
+		edges = append(edges, busElementEdges(sys)...)
+	}
+	return edges
+}
 
+func lineEdges(sys *System_Tree.System) []Edge {
 	var edges []Edge
 
 	for _, line := range sys.Lines {
-		var srcSID string
+		var src Endpoint
 
 		// 找这一条 Line 的 Src
 		for _, p := range line.Ps {
 			if p.Name == "Src" {
-				srcSID = parseSIDFromEndpoint(p.Value)
+				src = ParseEndpoint(p.Value)
 				break
 			}
 		}
-		if srcSID == "" {
+		if src.SID == "" {
 			continue
 		}
 
 		// 1）主 Line 上可能有一个 Dst
 		for _, p := range line.Ps {
 			if p.Name == "Dst" {
-				dstSID := parseSIDFromEndpoint(p.Value)
-				if dstSID != "" {
+				dst := ParseEndpoint(p.Value)
+				if dst.SID != "" {
 					edges = append(edges, Edge{
-						SrcSID: srcSID,
-						DstSID: dstSID,
+						Src:  src,
+						Dst:  dst,
+						Kind: KindLine,
 					})
 				}
 			}
@@ -83,11 +128,12 @@ func AnalyzeConnectionsInFile(dir, file string) ([]Edge, error) {
 		for _, br := range line.Branches {
 			for _, p := range br.Ps {
 				if p.Name == "Dst" {
-					dstSID := parseSIDFromEndpoint(p.Value)
-					if dstSID != "" {
+					dst := ParseEndpoint(p.Value)
+					if dst.SID != "" {
 						edges = append(edges, Edge{
-							SrcSID: srcSID,
-							DstSID: dstSID,
+							Src:  src,
+							Dst:  dst,
+							Kind: KindLine,
 						})
 					}
 				}
@@ -95,17 +141,291 @@ func AnalyzeConnectionsInFile(dir, file string) ([]Edge, error) {
 		}
 	}
 
-	return edges, nil
+	return edges
 }
 
-// "39#out:1" / "66#in:3" / "202#trigger" → "39" / "66" / "202"
-func parseSIDFromEndpoint(ep string) string {
+// dropInactive 去掉连到 Variant Source 未激活输入、从 Variant Sink 未激活输出出发的连线
+// 无法确定激活端口的 Variant Source / Sink 保留全部连线（由 Analysis_Process 警告）
+func dropInactive(sys *System_Tree.System, edges []Edge, sel Variant_Analysis.Selector) []Edge {
+	type variantPort struct {
+		blockType string
+		active    int
+	}
+	active := make(map[string]variantPort)
+	for _, b := range sys.Blocks {
+		if !Variant_Analysis.IsVariantPort(b) {
+			continue
+		}
+		if n, err := sel.ActivePort(b); err == nil {
+			active[b.SID] = variantPort{b.BlockType, n}
+		}
+	}
+	if len(active) == 0 {
+		return edges
+	}
+
+	var out []Edge
+	for _, e := range edges {
+		if v, ok := active[e.Dst.SID]; ok && v.blockType == Variant_Analysis.BlockVariantSource && e.Dst.Port == PortIn && e.Dst.Index != v.active {
+			continue
+		}
+		if v, ok := active[e.Src.SID]; ok && v.blockType == Variant_Analysis.BlockVariantSink && e.Src.Port == PortOut && e.Src.Index != v.active {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// ParseEndpoint 解析 "39#out:1" / "66#in:3" / "202#trigger"，保留端口类型和序号
+func ParseEndpoint(ep string) Endpoint {
 	ep = strings.TrimSpace(ep)
 	if ep == "" {
-		return ""
+		return Endpoint{}
+	}
+	idx := strings.Index(ep, "#")
+	if idx <= 0 {
+		return Endpoint{SID: ep}
+	}
+	e := Endpoint{SID: ep[:idx], Port: ep[idx+1:]}
+	if port, n, ok := strings.Cut(e.Port, ":"); ok {
+		e.Port = port
+		e.Index, _ = strconv.Atoi(n)
+	}
+	return e
+}
+
+// ======================== 隐式连接 ========================
+
+// 一个 Goto / From / Data Store 等 Block 及其所在位置
+//   - owner : 它在 sys 中归属的 Block（自己或包含它的 SubSystem）
+//   - sys   : 它实际所在的 system
+type placed struct {
+	block *System_Tree.Block
+	owner *System_Tree.Block
+	sys   *System_Tree.System
+}
+
+// collect 找出 sys 本层及所有下层中 BlockType 为 types 之一的 Block，记录它们在 sys 中的归属
+func collect(sys *System_Tree.System, types ...string) []placed {
+	var out []placed
+	for _, b := range sys.Blocks {
+		if slices.Contains(types, b.BlockType) {
+			out = append(out, placed{block: b, owner: b, sys: sys})
+		}
+		if b.Child != nil {
+			visited := map[*System_Tree.System]bool{sys: true}
+			collectInto(b.Child, b, types, visited, &out)
+		}
 	}
-	if idx := strings.Index(ep, "#"); idx > 0 {
-		return ep[:idx]
+	return out
+}
+
+func collectInto(sys *System_Tree.System, owner *System_Tree.Block, types []string, visited map[*System_Tree.System]bool, out *[]placed) {
+	if visited[sys] {
+		return
+	}
+	visited[sys] = true
+	for _, b := range sys.Blocks {
+		if slices.Contains(types, b.BlockType) {
+			*out = append(*out, placed{block: b, owner: owner, sys: sys})
+		}
+		if b.Child != nil {
+			collectInto(b.Child, owner, types, visited, out)
+		}
+	}
+}
+
+// within 判断 sys 是否为 scope 或其下层
+func within(sys, scope *System_Tree.System) bool {
+	for s := sys; s != nil; s = s.Parent {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// nearestScope 从 sys 往上找第一个含有 BlockType=blockType、prop=name 的 Block 的 system，找不到返回 nil
+func nearestScope(sys *System_Tree.System, blockType, prop, name string) *System_Tree.System {
+	for s := sys; s != nil; s = s.Parent {
+		for _, b := range s.Blocks {
+			if b.BlockType == blockType && b.Prop(prop) == name {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// addEdge 追加一条隐式连接，两端归到同一个 Block 或完全相同的连接不重复输出
+func addEdge(edges []Edge, src, dst *System_Tree.Block, kind, name string) []Edge {
+	if src == dst {
+		return edges
+	}
+	e := Edge{Src: Endpoint{SID: src.SID}, Dst: Endpoint{SID: dst.SID}, Kind: kind, Name: name}
+	if slices.Contains(edges, e) {
+		return edges
+	}
+	return append(edges, e)
+}
+
+// gotoEdges：Goto 与 GotoTag 相同且可见的 From 之间的连接
+//   - TagVisibility=local（默认）：只在 Goto 所在的 system 内可见
+//   - scoped：在最近的、含同名 GotoTagVisibility 的上层 system 及其下层可见
+//   - global：整个模型可见
+func gotoEdges(sys *System_Tree.System) []Edge {
+	var edges []Edge
+	froms := collect(sys, "From")
+	for _, g := range collect(sys, "Goto") {
+		tag := g.block.Prop("GotoTag")
+		if tag == "" {
+			continue
+		}
+
+		visibility := g.block.Prop("TagVisibility")
+		var scope *System_Tree.System
+		if visibility == "scoped" {
+			if scope = nearestScope(g.sys, "GotoTagVisibility", "GotoTag", tag); scope == nil {
+				continue
+			}
+		}
+
+		for _, f := range froms {
+			if f.block.Prop("GotoTag") != tag {
+				continue
+			}
+			switch visibility {
+			case "global":
+			case "scoped":
+				if !within(f.sys, scope) {
+					continue
+				}
+			default:
+				if f.sys != g.sys {
+					continue
+				}
+			}
+			edges = addEdge(edges, g.owner, f.owner, KindGoto, tag)
+		}
+	}
+	return edges
+}
+
+// dataStoreEdges：Data Store Write 与读同一个存储的 Data Store Read 之间的连接
+// 存储由 DataStoreName（默认 "A"）和最近的同名 Data Store Memory 所在 system 决定；
+// 都找不到 Data Store Memory 时视为同一个全局存储（Simulink.Signal）
+func dataStoreEdges(sys *System_Tree.System) []Edge {
+	var edges []Edge
+	storeName := func(b *System_Tree.Block) string {
+		if n := b.Prop("DataStoreName"); n != "" {
+			return n
+		}
+		return "A"
+	}
+
+	readers := collect(sys, "DataStoreRead")
+	for _, w := range collect(sys, "DataStoreWrite") {
+		name := storeName(w.block)
+		wScope := nearestScope(w.sys, "DataStoreMemory", "DataStoreName", name)
+		for _, r := range readers {
+			if storeName(r.block) != name {
+				continue
+			}
+			if nearestScope(r.sys, "DataStoreMemory", "DataStoreName", name) != wScope {
+				continue
+			}
+			edges = addEdge(edges, w.owner, r.owner, KindDataStore, name)
+		}
+	}
+	return edges
+}
+
+// busElementEdges：Bus Creator → Bus Selector 直接相连时，Bus Creator 各输入的来源 Block → 选出同名信号的输出所连的 Block
+// 信号名取 Bus Creator 输入线的 Name，没有时为 Simulink 默认的 signal<n>；只处理 sys 本层
+func busElementEdges(sys *System_Tree.System) []Edge {
+	var edges []Edge
+
+	blocks := make(map[string]*System_Tree.Block)
+	for _, b := range sys.Blocks {
+		blocks[b.SID] = b
+	}
+
+	// 端点 → 线：in["SID#in:n"] = 来源 SID 和信号名；out["SID#out:n"] = 目的 SID 列表
+	type source struct{ sid, name string }
+	in := make(map[string]source)
+	out := make(map[string][]string)
+	for _, line := range sys.Lines {
+		var src, name string
+		var dsts []string
+		for _, p := range line.Ps {
+			switch p.Name {
+			case "Src":
+				src = strings.TrimSpace(p.Value)
+			case "Name":
+				name = strings.TrimSpace(p.Value)
+			case "Dst":
+				dsts = append(dsts, strings.TrimSpace(p.Value))
+			}
+		}
+		for _, br := range line.Branches {
+			for _, p := range br.Ps {
+				if p.Name == "Dst" {
+					dsts = append(dsts, strings.TrimSpace(p.Value))
+				}
+			}
+		}
+		for _, d := range dsts {
+			in[d] = source{sid: ParseEndpoint(src).SID, name: name}
+			out[src] = append(out[src], ParseEndpoint(d).SID)
+		}
+	}
+
+	for _, sel := range sys.Blocks {
+		if sel.BlockType != "BusSelector" {
+			continue
+		}
+		bus, ok := in[sel.SID+"#in:1"]
+		if !ok {
+			continue
+		}
+		creator := blocks[bus.sid]
+		if creator == nil || creator.BlockType != "BusCreator" {
+			continue
+		}
+
+		// Bus Creator 的输入信号名 → 来源 Block
+		inputs := 2
+		if n, err := strconv.Atoi(creator.Prop("Inputs")); err == nil {
+			inputs = n
+		} else if names := creator.Prop("Inputs"); names != "" {
+			inputs = len(strings.Split(names, ","))
+		}
+		signals := make(map[string]*System_Tree.Block)
+		for i := 1; i <= inputs; i++ {
+			s, ok := in[fmt.Sprintf("%s#in:%d", creator.SID, i)]
+			if !ok || blocks[s.sid] == nil {
+				continue
+			}
+			name := s.name
+			if name == "" {
+				name = fmt.Sprintf("signal%d", i)
+			}
+			signals[name] = blocks[s.sid]
+		}
+
+		for i, name := range strings.Split(sel.Prop("OutputSignals"), ",") {
+			name = strings.TrimSpace(name)
+			src := signals[name]
+			if src == nil {
+				continue
+			}
+			for _, dstSID := range out[fmt.Sprintf("%s#out:%d", sel.SID, i+1)] {
+				if dst := blocks[dstSID]; dst != nil {
+					edges = addEdge(edges, src, dst, KindBusElement, name)
+				}
+			}
+		}
 	}
-	return ep
+	return edges
 }
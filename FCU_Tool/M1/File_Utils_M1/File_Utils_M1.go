@@ -1,114 +1,333 @@
 package File_Utils_M1
 
 import (
-	"archive/zip"
-	"bufio"
-	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 
+	"FCU_Tools/Config"
+	"FCU_Tools/LDI"
 	"FCU_Tools/M1/M1_Public_Data"
+	"FCU_Tools/M1/MDL_Reader"
+	"FCU_Tools/M1/Model_IR"
+	"FCU_Tools/M1/SLX_Reader"
+	"FCU_Tools/Public_data"
 )
 
-// 2. 读取 Windows 路径：控制台提示 + 读入 + 保存到 M1_Public_Data.SrcPath
-func ReadWindowsPath() {
-	fmt.Print("请输入一个 Windows 路径： ")
-
-	reader := bufio.NewReader(os.Stdin)
-	input, err := reader.ReadString('\n')
-	if err != nil {
-		fmt.Println("读取输入失败：", err)
-		return
+// 2. 找出要分析的模型文件（slx / mdl），返回其路径
+//   - inputs.model_list：列表文件中的模型（按列表顺序）
+//   - inputs.model_include / model_exclude：在 SrcPath 下递归查找，按路径排序
+//   - 两者都没有时按原来的规则，从 SrcPath 下的子文件夹中找出同名 slx 文件（按文件夹名排序）
+//       SrcPath/
+//         ├─ ModelA/  →  ModelA/ModelA.slx
+//         ├─ ModelB/  →  ModelB/ModelB.slx
+//         ├─ ModelC/  →  ModelC/ModelC.mdl（没有 slx 时取同名的文本格式模型）
+// 模型名（文件名去掉扩展名，不区分大小写）重复时给出警告并列出所有路径，只取第一个
+// 模型列表读取失败、列表中没有可用的模型、或最终一个模型也没有找到时返回错误（不以 0 个模型继续）
+func FindSlxModels(ws *M1_Public_Data.Workspace) ([]string, error) {
+	var slxPaths []string
+	if ws.ModelList != "" {
+		listed, err := readModelList(ws.ModelList)
+		if err != nil {
+			return nil, err
+		}
+		if len(listed) == 0 {
+			return nil, fmt.Errorf("模型列表 [%s] 中没有可分析的模型", ws.ModelList)
+		}
+		slxPaths = append(slxPaths, listed...)
 	}
-
-	input = strings.TrimSpace(input)
-	M1_Public_Data.SrcPath = input
+	if len(ws.Include) > 0 {
+		slxPaths = append(slxPaths, discoverModels(ws.SrcPath, ws.Include, ws.Exclude)...)
+	}
+	if ws.ModelList == "" && len(ws.Include) == 0 {
+		slxPaths = findByFolder(ws.SrcPath)
+	}
+	slxPaths = uniqueModels(slxPaths)
+	if len(slxPaths) == 0 {
+		return nil, fmt.Errorf("没有找到要分析的模型 [%s]", ws.SrcPath)
+	}
+	return slxPaths, nil
 }
 
-// 3. 从 SrcPath 下的子文件夹中，复制同名 slx 文件到 BuildDir
-//   SrcPath/
-//     ├─ ModelA/  →  ModelA/ModelA.slx  复制到  BuildDir/ModelA.slx
-//     ├─ ModelB/  →  ModelB/ModelB.slx  复制到  BuildDir/ModelB.slx
-// 同时在 TxtDir 下创建同名的 txt 文件：ModelA.txt、ModelB.txt
-func CopySlxToBuild() {
-	srcRoot := M1_Public_Data.SrcPath
-	dstRoot := M1_Public_Data.BuildDir
-	txtRoot := M1_Public_Data.TxtDir
-
+// findByFolder 按 <Folder>/<Folder>.slx（或 .mdl）查找
+func findByFolder(srcRoot string) []string {
 	if srcRoot == "" {
-		fmt.Println("SrcPath 为空，请先调用 ReadWindowsPath() 输入路径")
-		return
-	}
-	if dstRoot == "" {
-		fmt.Println("BuildDir 为空，请先调用 SetWorkDir() 初始化工作空间")
-		return
-	}
-	if txtRoot == "" {
-		fmt.Println("TxtDir 为空，请检查 SetWorkDir() 是否正确设置")
-		return
+		fmt.Println("SrcPath 为空，请在配置 inputs.models_dir 或命令行 --models 中指定模型路径")
+		return nil
 	}
 
 	entries, err := os.ReadDir(srcRoot)
 	if err != nil {
 		fmt.Println("无法读取 SrcPath 目录：", err)
-		return
+		return nil
 	}
 
+	var slxPaths []string
 	for _, e := range entries {
 		if !e.IsDir() {
 			continue
 		}
 
 		folderName := e.Name()
-		slxPath := filepath.Join(srcRoot, folderName, folderName+".slx")
-		if _, err := os.Stat(slxPath); err != nil {
-			// 没有同名 slx，跳过
+		for _, ext := range modelExts {
+			slxPath := filepath.Join(srcRoot, folderName, folderName+ext)
+			if _, err := os.Stat(slxPath); err == nil {
+				slxPaths = append(slxPaths, slxPath)
+				break
+			}
+		}
+		// 没有同名 slx / mdl，跳过
+	}
+	return slxPaths
+}
+
+// discoverModels 在 srcRoot 下递归查找符合 include、不符合 exclude 的模型文件
+// 符合 exclude 的文件夹整个跳过；同一文件夹中同名的 slx 和 mdl 只取 slx（不算模型名重复）
+func discoverModels(srcRoot string, include, exclude []string) []string {
+	if srcRoot == "" {
+		fmt.Println("SrcPath 为空，请在配置 inputs.models_dir 或命令行 --models 中指定模型路径")
+		return nil
+	}
+
+	var slxPaths []string
+	err := filepath.WalkDir(srcRoot, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcRoot, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if matchAny(exclude, rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && isModelFile(p) && matchAny(include, rel) {
+			slxPaths = append(slxPaths, p)
+		}
+		return nil
+	})
+	if err != nil {
+		Public_data.Warnf("查找模型失败 [%s]：%v", srcRoot, err)
+	}
+	return preferSlx(slxPaths)
+}
+
+// preferSlx 对同一文件夹中同名（不区分大小写）的模型文件只保留 modelExts 中靠前的扩展名，位置取先出现的那个
+// WalkDir 按文件名排序，X.mdl 会在 X.slx 之前
+func preferSlx(slxPaths []string) []string {
+	var out []string
+	index := make(map[string]int)
+	for _, p := range slxPaths {
+		key := strings.ToLower(strings.TrimSuffix(p, filepath.Ext(p)))
+		if i, ok := index[key]; ok {
+			if extRank(p) < extRank(out[i]) {
+				out[i] = p
+			}
 			continue
 		}
+		index[key] = len(out)
+		out = append(out, p)
+	}
+	return out
+}
+
+func extRank(name string) int {
+	for i, ext := range modelExts {
+		if strings.EqualFold(filepath.Ext(name), ext) {
+			return i
+		}
+	}
+	return len(modelExts)
+}
 
-		// 目标 slx 文件路径：BuildDir/同名.slx
-		dstPath := filepath.Join(dstRoot, folderName+".slx")
+// readModelList 读取模型列表文件：每行一个模型文件路径，相对路径以列表文件所在文件夹为准，空行和 # 开头的行忽略
+// 单独的一行有问题时只警告并跳过；列表文件本身读不了时返回错误
+func readModelList(listPath string) ([]string, error) {
+	data, err := os.ReadFile(listPath)
+	if err != nil {
+		return nil, fmt.Errorf("无法读取模型列表 [%s]：%v", listPath, err)
+	}
 
-		// 复制 slx 文件
-		if err := copyFile(slxPath, dstPath); err != nil {
-			fmt.Printf("复制失败 [%s] → [%s]：%v\n", slxPath, dstPath, err)
+	var slxPaths []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p := filepath.FromSlash(line)
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(filepath.Dir(listPath), p)
+		}
+		if !isModelFile(p) {
+			Public_data.Warnf("模型列表 [%s] 第 %d 行不是 slx / mdl 文件：%s", listPath, i+1, line)
+			continue
+		}
+		if _, err := os.Stat(p); err != nil {
+			Public_data.Warnf("模型列表 [%s] 第 %d 行的模型不存在：%s", listPath, i+1, p)
 			continue
 		}
+		slxPaths = append(slxPaths, p)
+	}
+	return slxPaths, nil
+}
+
+// uniqueModels 去掉重复的路径；模型名重复时警告并列出所有路径，只保留第一个
+func uniqueModels(slxPaths []string) []string {
+	var out []string
+	byName := make(map[string][]string)
+	var names []string
+	for _, p := range slxPaths {
+		name := strings.ToLower(strings.TrimSuffix(filepath.Base(p), filepath.Ext(p)))
+		if slices.ContainsFunc(byName[name], func(q string) bool { return filepath.Clean(q) == filepath.Clean(p) }) {
+			continue
+		}
+		if len(byName[name]) == 0 {
+			names = append(names, name)
+			out = append(out, p)
+		}
+		byName[name] = append(byName[name], p)
+	}
+	for _, name := range names {
+		if paths := byName[name]; len(paths) > 1 {
+			Public_data.Warnf("模型名 %s 重复，使用 [%s]，忽略：%s", strings.TrimSuffix(filepath.Base(paths[0]), filepath.Ext(paths[0])),
+				paths[0], strings.Join(paths[1:], ", "))
+		}
+	}
+	return out
+}
+
+// matchAny 判断相对路径 rel（"/" 分隔）是否符合 patterns 中任意一个 glob
+// "**" 匹配 0 个或多个文件夹；没有 "/" 的模式只和文件名比较
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+				return true
+			}
+			continue
+		}
+		if matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(pattern, segs []string) bool {
+	if len(pattern) == 0 {
+		return len(segs) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segs); i++ {
+			if matchSegments(pattern[1:], segs[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segs) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segs[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segs[1:])
+}
+
+// 模型文件扩展名，同名时优先 slx
+var modelExts = []string{".slx", MDL_Reader.Ext}
+
+// isModelFile 判断是否为 slx / mdl 模型文件
+func isModelFile(name string) bool {
+	for _, ext := range modelExts {
+		if strings.EqualFold(filepath.Ext(name), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// 找出 LibraryDirs（inputs.library_dirs）下所有的库 slx / mdl（包括子文件夹），库名为文件名去掉扩展名
+// 同名的库只取第一个，并给出警告
+func FindLibraries(ws *M1_Public_Data.Workspace) []string {
+	var libPaths []string
+	seen := make(map[string]string)
 
-		// 在 TxtDir 下创建同名 txt 文件
-		txtPath := filepath.Join(txtRoot, folderName+".txt")
-		f, err := os.Create(txtPath) // 每次运行重建/清空
+	for _, dir := range ws.LibraryDirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !isModelFile(path) {
+				return nil
+			}
+			name := strings.TrimSuffix(d.Name(), filepath.Ext(path))
+			if first, ok := seen[name]; ok {
+				Public_data.Warnf("库 %s 重复，使用 [%s]，忽略 [%s]", name, first, path)
+				return nil
+			}
+			seen[name] = path
+			libPaths = append(libPaths, path)
+			return nil
+		})
 		if err != nil {
-			fmt.Printf("无法创建 txt 文件 [%s]：%v\n", txtPath, err)
+			Public_data.Warnf("无法读取库目录 [%s]：%v", dir, err)
+		}
+	}
+	return libPaths
+}
+
+// 复制 slx 文件到 BuildDir（仅在 metrics.m1.extract 打开时用于调试，分析本身直接读取 slx）
+//   ModelA/ModelA.slx  复制到  BuildDir/ModelA.slx
+//   mdl 本身是文本，同样复制过去，不需要解压
+func CopySlxToBuild(ws *M1_Public_Data.Workspace, slxPaths []string) {
+	dstRoot := ws.BuildDir
+	if dstRoot == "" {
+		fmt.Println("BuildDir 为空，请先调用 NewWorkspace() 初始化工作空间")
+		return
+	}
+
+	for _, slxPath := range slxPaths {
+		// 目标 slx 文件路径：BuildDir/同名.slx
+		dstPath := filepath.Join(dstRoot, filepath.Base(slxPath))
+
+		// 复制 slx 文件
+		if err := copyFile(slxPath, dstPath); err != nil {
+			fmt.Printf("复制失败 [%s] → [%s]：%v\n", slxPath, dstPath, err)
 			continue
 		}
-		_ = f.Close()
 	}
 }
 
-// 4. 解压 BuildDir 下的 slx 文件到同名目录
+// 解压 BuildDir 下的 slx 文件到同名目录（同上，仅用于调试时查看 xml）
 //   BuildDir/
 //     ├─ ModelA.slx  → 解压到 BuildDir/ModelA/...
 //     ├─ ModelB.slx  → 解压到 BuildDir/ModelB/...
-func UnzipSlxFiles() {
-	buildRoot := M1_Public_Data.BuildDir
+// 不安全的 slx（zip-slip、符号链接、超过大小上限）不解压，全部以 SLX_Reader.ArchiveError 汇总返回
+func UnzipSlxFiles(ws *M1_Public_Data.Workspace) error {
+	buildRoot := ws.BuildDir
 	if buildRoot == "" {
-		fmt.Println("BuildDir 为空，请先调用 SetWorkDir() 初始化工作空间")
-		return
+		return fmt.Errorf("BuildDir 为空，请先调用 NewWorkspace() 初始化工作空间")
 	}
 
 	entries, err := os.ReadDir(buildRoot)
 	if err != nil {
-		fmt.Println("无法读取 BuildDir 目录：", err)
-		return
+		return fmt.Errorf("无法读取 BuildDir 目录: %w", err)
 	}
 
+	var errs []error
 	for _, e := range entries {
 		if e.IsDir() {
 			continue
@@ -126,11 +345,13 @@ func UnzipSlxFiles() {
 		// 确保解压目录是干净的
 		_ = os.RemoveAll(destDir)
 
-		if err := unzipOne(slxPath, destDir); err != nil {
-			fmt.Printf("解压失败 [%s] → [%s]：%v\n", slxPath, destDir, err)
-			continue
+		if err := SLX_Reader.Extract(slxPath, destDir, ws.Limits); err != nil {
+			// 不留下解压了一半的目录
+			_ = os.RemoveAll(destDir)
+			errs = append(errs, err)
 		}
 	}
+	return errors.Join(errs...)
 }
 
 // 简单的文件复制工具
@@ -158,103 +379,49 @@ func copyFile(src, dst string) error {
 	return nil
 }
 
-// 解压单个 slx(zip) 到 destDir
-func unzipOne(zipPath, destDir string) error {
-	r, err := zip.OpenReader(zipPath)
-	if err != nil {
-		return err
-	}
-	defer r.Close()
-
-	for _, f := range r.File {
-		targetPath := filepath.Join(destDir, f.Name)
-
-		// 目录
-		if f.FileInfo().IsDir() {
-			if err := os.MkdirAll(targetPath, 0755); err != nil {
-				return err
-			}
-			continue
-		}
-
-		// 确保上级目录存在
-		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
-			return err
-		}
-
-		rc, err := f.Open()
-		if err != nil {
-			return err
-		}
-
-		outFile, err := os.Create(targetPath)
-		if err != nil {
-			rc.Close()
-			return err
-		}
-
-		if _, err := io.Copy(outFile, rc); err != nil {
-			outFile.Close()
-			rc.Close()
-			return err
-		}
-
-		outFile.Close()
-		rc.Close()
-	}
-	return nil
-}
-
 // ===================== M1 LDI 生成相关 =====================
 
-// 用于从 txt 中解析出来的节点信息
+// 用于 M1 计算的节点信息（由中间表示 Model_IR 展开）
 type m1Node struct {
 	Level          int
 	Name           string
 	SID            string
-	Father         string
+	Parent         *m1Node   // 上一层节点（L1 为 nil）
+	Children       []*m1Node // 直接子节点
 	Ports          int     // 当前节点自己的端口个数（包括 virtual port）
-	CSPorts        int     // 仅 L1 的 C-S 端口数
+	PortKinds      map[string]int // 各类端口数（Config.PortKindXxx → 个数），用于按计算式加权
 	ChildCount     int     // 直接子节点个数
-	ChildPorts     int     // 直接子节点端口数之和
-	EffectivePorts float64 // L1: 加权端口数; 其他层: 等于 Ports
+	ChildPorts     float64 // 直接子节点加权端口数之和
+	EffectivePorts float64 // 按本层权重加权后的端口数
 	Coverage       float64 // 计算出的 m1
-}
+	SourceBlock    string  // 库链接的来源（已展开的库链接才有）
+	InLibrary      bool    // 来自库（库链接展开后的内容）
 
-// LDI XML 结构
-type ldiProperty struct {
-	XMLName xml.Name `xml:"property"`
-	Name    string   `xml:"name,attr"`
-	Value   string   `xml:",chardata"`
+	ControlPorts map[string]int // 控制端口数（Model_IR.ControlXxx → 个数），已包含在 Ports 中，另外输出
+	Chart        *Model_IR.Chart // Stateflow chart 的结构统计（chart 才有）
 }
 
-type ldiElement struct {
-	XMLName  xml.Name      `xml:"element"`
-	Name     string        `xml:"name,attr"`
-	Property []ldiProperty `xml:"property"`
+// 控制端口在 LDI / _m1.txt 中的输出顺序和名字（只输出个数大于 0 的）
+var controlOutputs = []struct{ control, property, label string }{
+	{Model_IR.ControlTrigger, "m1.trigger_ports", "Trigger"},
+	{Model_IR.ControlEnable, "m1.enable_ports", "Enable"},
+	{Model_IR.ControlFunctionCall, "m1.function_call_ports", "FunctionCall"},
+	{Model_IR.ControlIfAction, "m1.ifaction_ports", "IfAction"},
 }
 
-type ldiRoot struct {
-	XMLName xml.Name     `xml:"ldi"`
-	Items   []ldiElement `xml:"element"`
-}
+// LDI 中标记来自库的元素：库链接本身和展开的内容都带 TagLibrary，库链接另外带 "library:<来源>"，便于统计复用
+const TagLibrary = "library"
 
-// 6. 根据 TxtDir 下的 txt 文件生成对应的 ldi.xml
-//    例如 TurnLight.txt -> TurnLight.ldi.xml
+// 5. 根据分析结果（中间表示）生成对应的 ldi.xml
+//    例如 TurnLight -> LDIDir/TurnLight.ldi.xml
 //    规则：如果存在 N 层，只对 1..N-1 层计算并输出 m1，最底层 N 不输出
 //    同时在 TxtDir 下生成 XXX_m1.txt，总结每层的 Ports / 子节点个数 / 子端口数
-func GenerateM1LDIFromTxt() {
-	txtRoot := M1_Public_Data.TxtDir
-	ldiRoot := M1_Public_Data.LDIDir
+func GenerateM1LDI(ws *M1_Public_Data.Workspace, models []*Model_IR.Model) {
+	txtRoot := ws.TxtDir
+	ldiRoot := ws.LDIDir
 
 	if txtRoot == "" || ldiRoot == "" {
-		fmt.Println("TxtDir 或 LDIDir 为空，请检查 SetWorkDir 是否正确设置")
-		return
-	}
-
-	entries, err := os.ReadDir(txtRoot)
-	if err != nil {
-		fmt.Println("读取 TxtDir 失败:", err)
+		fmt.Println("TxtDir 或 LDIDir 为空，请检查 NewWorkspace 是否正确设置")
 		return
 	}
 
@@ -264,203 +431,101 @@ func GenerateM1LDIFromTxt() {
 		return
 	}
 
-	for _, e := range entries {
-		if e.IsDir() {
-			continue
-		}
-		name := e.Name()
-		if strings.ToLower(filepath.Ext(name)) != ".txt" {
-			continue
-		}
+	for _, model := range models {
+		modelName := model.Name
 
-		txtPath := filepath.Join(txtRoot, name)
-		modelName := strings.TrimSuffix(name, filepath.Ext(name))
-
-		nodes, err := parseM1NodesFromTxt(txtPath)
-		if err != nil {
-			fmt.Printf("解析 txt 失败 [%s]: %v\n", txtPath, err)
-			continue
-		}
+		nodes := nodesFromModel(model)
 		if len(nodes) == 0 {
-			fmt.Printf("txt 中没有解析到节点 [%s]\n", txtPath)
+			fmt.Printf("模型中没有分析到节点 [%s]\n", modelName)
 			continue
 		}
 
-		computeM1ForNodes(nodes)
+		computeM1ForNodes(nodes, ws.Options.Formula)
 
-		// 生成 ldi.xml（这里把 txt 文件名传进去，用于替换 element name 前缀）
+		// 生成 ldi.xml（这里把模型名传进去，用于替换 element name 前缀）
 		ldiPath := filepath.Join(ldiRoot, modelName+".ldi.xml")
-		if err := writeM1LDI(ldiPath, modelName, nodes); err != nil {
+		if err := writeM1LDI(ldiPath, modelName, nodes, ws.Options.StateflowComplexity); err != nil {
 			fmt.Printf("写入 LDI 失败 [%s]: %v\n", ldiPath, err)
 			// 不中断，继续生成 m1.txt
-		} else {
-			//fmt.Printf("已生成 LDI：%s\n", ldiPath)
 		}
 
 		// 生成 XXX_m1.txt
 		statsPath := filepath.Join(txtRoot, modelName+"_m1.txt")
-		if err := writeM1StatsTxt(statsPath, nodes); err != nil {
+		if err := writeM1StatsTxt(statsPath, nodes, ws.Options.Formula); err != nil {
 			fmt.Printf("写入 m1 统计失败 [%s]: %v\n", statsPath, err)
-		} else {
-			//fmt.Printf("已生成 m1 统计：%s\n", statsPath)
 		}
 	}
 }
 
-// 解析一个 txt，把所有 [Lx] block 和 [Lx Port]/[Lx virtual Port] 全部解析出来
-func parseM1NodesFromTxt(txtPath string) ([]*m1Node, error) {
-	f, err := os.Open(txtPath)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
+// 把中间表示展开成节点列表（顺序与原 txt 相同），并统计每个节点自己的端口数
+// 端口数包括虚拟端口；另外按类别计数，用于按 metrics.m1.formula 加权
+func nodesFromModel(model *Model_IR.Model) []*m1Node {
+	var nodes []*m1Node
+	byBlock := make(map[*Model_IR.Block]*m1Node)
 
-	scanner := bufio.NewScanner(f)
+	model.Walk(func(b, parent *Model_IR.Block) {
+		n := &m1Node{
+			Level: b.Level,
+			Name:  b.Name,
+			SID:   b.SID,
+			Ports: len(b.Ports),
 
-	var (
-		nodes   []*m1Node
-		curNode *m1Node
-	)
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.TrimSpace(line) == "" {
-			continue
+			InLibrary: b.InLibrary,
+			Chart:     b.Chart,
 		}
-
-		// Block 行：以 "[L" 开头且没有前导 Tab
-		if strings.HasPrefix(line, "[L") {
-			trim := strings.TrimSpace(line)
-			levelRe := regexp.MustCompile(`^\[L(\d+)\]`)
-			m := levelRe.FindStringSubmatch(trim)
-			if len(m) >= 2 {
-				level, name, sid, father, ok := parseBlockLineInfo(trim)
-				if !ok {
-					continue
-				}
-				node := &m1Node{
-					Level:  level,
-					Name:   name,
-					SID:    sid,
-					Father: father,
-				}
-				nodes = append(nodes, node)
-				curNode = node
-				continue
-			}
+		if b.Library != nil && b.Library.Status == Model_IR.RefResolved {
+			n.SourceBlock = strings.Join(strings.Fields(b.Library.SourceBlock), " ")
 		}
-
-		// 端口行形如：\t[L1 Port] 或 \t[L2 virtual Port]
-		if strings.HasPrefix(line, "\t[L") {
-			trim := strings.TrimLeft(line, "\t")
-			endIdx := strings.Index(trim, "]")
-			if endIdx <= 0 {
-				continue
-			}
-			header := trim[1:endIdx] // e.g. "L1 Port" or "L2 virtual Port"
-
-			level, portType, ok := parsePortLineLevelAndType(header, trim)
-			if !ok {
-				continue
+		for _, p := range b.Ports {
+			if n.PortKinds == nil {
+				n.PortKinds = make(map[string]int)
 			}
-
-			// 只在当前节点的同层端口上计数
-			if curNode != nil && curNode.Level == level {
-				curNode.Ports++
-				if portType == "C-S" {
-					curNode.CSPorts++
+			n.PortKinds[portKind(p)]++
+			if p.Control != "" {
+				if n.ControlPorts == nil {
+					n.ControlPorts = make(map[string]int)
 				}
+				n.ControlPorts[p.Control]++
 			}
 		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-	return nodes, nil
-}
-
-// 从类似 "[L2 Port]" 或 "[L2 virtual Port]" 里解析出 Level
-// 同时从整行里解析 PortType（只用来识别 C-S port）
-func parsePortLineLevelAndType(header string, fullLine string) (int, string, bool) {
-	fields := strings.Fields(header) // e.g. ["L2","Port"] or ["L2","virtual","Port"]
-	if len(fields) == 0 {
-		return 0, "", false
-	}
-	levelStr := strings.TrimPrefix(fields[0], "L")
-	level, err := strconv.Atoi(levelStr)
-	if err != nil {
-		return 0, "", false
-	}
-
-	portType := ""
-	if idx := strings.Index(fullLine, "PortType="); idx >= 0 {
-		rest := fullLine[idx+len("PortType="):]
-		ptFields := strings.Fields(rest)
-		if len(ptFields) > 0 {
-			portType = strings.TrimSpace(ptFields[0])
+		if parent != nil {
+			n.Parent = byBlock[parent]
+			n.Parent.Children = append(n.Parent.Children, n)
 		}
-	}
-	return level, portType, true
+		byBlock[b] = n
+		nodes = append(nodes, n)
+	})
+	return nodes
 }
 
-// 解析类似：
-// [L2] Name: HazardCtrlLogic	BlockType=SubSystem	SID=66       	FatherNode=TurnLight_Runnable_10ms_sys
-func parseBlockLineInfo(trim string) (int, string, string, string, bool) {
-	// 解析层级
-	levelRe := regexp.MustCompile(`^\[L(\d+)\]`)
-	m := levelRe.FindStringSubmatch(trim)
-	if len(m) < 2 {
-		return 0, "", "", "", false
-	}
-	level, err := strconv.Atoi(m[1])
-	if err != nil {
-		return 0, "", "", "", false
-	}
-
-	// Name 在 "Name:" 和 "BlockType=" 之间
-	name := ""
-	if nameIdx := strings.Index(trim, "Name:"); nameIdx >= 0 {
-		after := trim[nameIdx+len("Name:"):]
-		btIdx := strings.Index(after, "BlockType=")
-		if btIdx > 0 {
-			name = strings.TrimSpace(after[:btIdx])
-		} else {
-			// 没有 BlockType= 时，取到行尾
-			name = strings.TrimSpace(after)
-		}
-	}
-
-	// SID=
-	sid := ""
-	if sidIdx := strings.Index(trim, "SID="); sidIdx >= 0 {
-		after := trim[sidIdx+len("SID="):]
-		sidFields := strings.Fields(after)
-		if len(sidFields) > 0 {
-			sid = sidFields[0]
-		}
-	}
-
-	// FatherNode=
-	father := ""
-	if faIdx := strings.Index(trim, "FatherNode="); faIdx >= 0 {
-		after := trim[faIdx+len("FatherNode="):]
-		faFields := strings.Fields(after)
-		if len(faFields) > 0 {
-			father = faFields[0]
-		}
-	}
-
-	if name == "" {
-		return 0, "", "", "", false
+// 端口在 M1 计算式中的类别（Config.M1PortKinds 中第一个符合的）
+func portKind(p Model_IR.Port) string {
+	switch {
+	case p.Control == Model_IR.ControlTrigger:
+		return Config.PortKindTrigger
+	case p.Control == Model_IR.ControlEnable:
+		return Config.PortKindEnable
+	case p.Control == Model_IR.ControlFunctionCall:
+		return Config.PortKindFunctionCall
+	case p.Control == Model_IR.ControlIfAction:
+		return Config.PortKindIfAction
+	case p.PortType == Model_IR.PortCS:
+		return Config.PortKindCS
+	case p.Virtual && p.Kind != "":
+		return Config.PortKindImplicit
+	case p.Virtual:
+		return Config.PortKindVirtual
+	default:
+		return Config.PortKindSR
 	}
-	return level, name, sid, father, true
 }
 
-// 按你的规则计算每个节点的 m1
+// 按 metrics.m1.formula 计算每个节点的 m1：M1 = P × N × C
+// - P 为本节点按本层权重加权后的端口数（EffectivePorts），N 为子节点数，C 为子节点 P 之和
 // - 有 N 层，只对 1..N-1 层计算（最后一层 Level=N 的节点 coverage=0）
 // - 同时填充 ChildCount / ChildPorts / EffectivePorts，供 ldi 和 _m1.txt 共用
-func computeM1ForNodes(nodes []*m1Node) {
+// 默认权重（L1 的 C-S 端口 1.2，其他为 1）与原来的计算方式相同
+func computeM1ForNodes(nodes []*m1Node, formula Config.M1Formula) {
 	if len(nodes) == 0 {
 		return
 	}
@@ -471,24 +536,21 @@ func computeM1ForNodes(nodes []*m1Node) {
 		if n.Level > maxLevel {
 			maxLevel = n.Level
 		}
-		if n.Level == 1 {
-			normalPorts := n.Ports - n.CSPorts
-			if normalPorts < 0 {
-				normalPorts = 0
+		// 权重为 1 的端口先按整数累加，默认权重下结果与原来的 normalPorts + CSPorts*1.2 完全相同
+		weights := formula.WeightsFor(n.Level)
+		unit, weighted := 0, 0.0
+		for _, kind := range Config.M1PortKinds {
+			cnt := n.PortKinds[kind]
+			if w := weights.Weight(kind); w == 1 {
+				unit += cnt
+			} else {
+				weighted += float64(cnt) * w
 			}
-			n.EffectivePorts = float64(normalPorts) + float64(n.CSPorts)*1.2
-		} else {
-			n.EffectivePorts = float64(n.Ports)
 		}
+		n.EffectivePorts = float64(unit) + weighted
 	}
 
-	// 2) 按层级分组，方便查找子节点
-	levelMap := make(map[int][]*m1Node)
-	for _, n := range nodes {
-		levelMap[n.Level] = append(levelMap[n.Level], n)
-	}
-
-	// 3) 逐个节点计算 m1 和子节点统计
+	// 2) 逐个节点计算 m1 和子节点统计
 	for _, n := range nodes {
 		// 默认初始化
 		n.ChildCount = 0
@@ -500,21 +562,13 @@ func computeM1ForNodes(nodes []*m1Node) {
 			continue
 		}
 
-		childLevel := n.Level + 1
-		children := levelMap[childLevel]
-
-		// 筛选真正的“直属子节点”：FatherName == 当前节点 Name
-		var realChildren []*m1Node
-		for _, c := range children {
-			if c.Father == n.Name {
-				realChildren = append(realChildren, c)
-			}
-		}
+		// 直属子节点（中间表示中挂在当前节点下的 Block）
+		realChildren := n.Children
 
-		// 子节点端口数之和
-		pChildSum := 0
+		// 子节点加权端口数之和
+		pChildSum := 0.0
 		for _, c := range realChildren {
-			pChildSum += c.Ports
+			pChildSum += c.EffectivePorts
 		}
 
 		n.ChildCount = len(realChildren)
@@ -525,13 +579,7 @@ func computeM1ForNodes(nodes []*m1Node) {
 			continue
 		}
 
-		// 仅 L1 节点按 C-S 端口做 1.2 计权
-		if n.Level == 1 {
-			n.Coverage = n.EffectivePorts * float64(n.ChildCount) * float64(n.ChildPorts)
-		} else {
-			// L2 及之后（直到倒数第二层）：纯粹数量算法
-			n.Coverage = float64(n.Ports) * float64(n.ChildCount) * float64(n.ChildPorts)
-		}
+		n.Coverage = n.EffectivePorts * float64(n.ChildCount) * n.ChildPorts
 	}
 }
 
@@ -539,53 +587,25 @@ func computeM1ForNodes(nodes []*m1Node) {
 // L1: Name
 // L2: Father.Name  => L1.Name + "." + L2.Name
 // L3: L1.Name + "." + L2.Name + "." + L3.Name
-func buildHierNameForNode(n *m1Node, all []*m1Node) string {
-	if n.Level <= 1 || n.Father == "" {
-		return n.Name
-	}
-
-	// 先构建一个索引：level+name -> 节点
-	type key struct {
-		Level int
-		Name  string
-	}
-	index := make(map[key]*m1Node)
-	for _, x := range all {
-		index[key{Level: x.Level, Name: x.Name}] = x
-	}
-
+func buildHierNameForNode(n *m1Node) string {
 	// 从当前节点往上回溯到 L1
-	var chain []*m1Node
-	cur := n
-	for cur != nil {
-		chain = append(chain, cur)
-		if cur.Level == 1 || cur.Father == "" {
-			break
-		}
-		parent, ok := index[key{Level: cur.Level - 1, Name: cur.Father}]
-		if !ok {
-			break
-		}
-		cur = parent
+	var names []string
+	for cur := n; cur != nil; cur = cur.Parent {
+		names = append(names, cur.Name)
 	}
 
-	// chain 现在是 [当前, 父, 父的父, ...]，需要反转
-	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
-		chain[i], chain[j] = chain[j], chain[i]
-	}
-
-	names := make([]string, 0, len(chain))
-	for _, x := range chain {
-		names = append(names, x.Name)
+	// names 现在是 [当前, 父, 父的父, ...]，需要反转
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
 	}
 	return strings.Join(names, ".")
 }
 
-// 把 element name 的“第一个段名”替换为 txt 文件名（modelName）
+// 把 element name 的“第一个段名”替换为模型名（modelName）
 // - "RUNNABLE" -> "CL1CM1"
 // - "RUNNABLE.DATA" -> "CL1CM1.DATA"
 // - "RUNNABLE.DATA.X" -> "CL1CM1.DATA.X"
-func replaceElementPrefixWithTxtName(elementName, modelName string) string {
+func replaceElementPrefixWithModelName(elementName, modelName string) string {
 	modelName = strings.TrimSpace(modelName)
 	if modelName == "" {
 		return elementName
@@ -597,9 +617,10 @@ func replaceElementPrefixWithTxtName(elementName, modelName string) string {
 }
 
 // 把 nodes 写成一个 ldi.xml 文件
-// 注意：只输出 1..maxLevel-1 层的节点，最底层 Level=maxLevel 的节点完全不写入
-func writeM1LDI(ldiPath string, modelName string, nodes []*m1Node) error {
-	var root ldiRoot
+// 注意：只输出 1..maxLevel-1 层的节点，最底层 Level=maxLevel 的节点不写入 coverage.m1
+// sfComplexity 为 true 时 Stateflow chart 另外输出 m1.sf_states / m1.sf_transitions / m1.sf_depth（最底层的 chart 只输出这三项）
+func writeM1LDI(ldiPath string, modelName string, nodes []*m1Node, sfComplexity bool) error {
+	doc := LDI.New()
 
 	// 计算全局最大层级
 	maxLevel := 0
@@ -609,59 +630,55 @@ func writeM1LDI(ldiPath string, modelName string, nodes []*m1Node) error {
 		}
 	}
 
-	// 为了输出稳定性：按 level 升序，再按层级名排序
-	type namedNode struct {
-		Node *m1Node
-		Path string
-	}
-	var list []namedNode
+	// 输出顺序由 LDI.Document 统一按 element 名排序，保证稳定
 	for _, n := range nodes {
-		// 跳过最底层：不写入 LDI
-		if n.Level >= maxLevel {
+		// 最底层：除 chart 的结构统计外不写入 LDI
+		bottom := n.Level >= maxLevel
+		if bottom && !(sfComplexity && n.Chart != nil) {
 			continue
 		}
-		path := buildHierNameForNode(n, nodes)
-		list = append(list, namedNode{Node: n, Path: path})
-	}
-	sort.Slice(list, func(i, j int) bool {
-		if list[i].Node.Level != list[j].Node.Level {
-			return list[i].Node.Level < list[j].Node.Level
-		}
-		return list[i].Path < list[j].Path
-	})
-
-	for _, nn := range list {
-		n := nn.Node
-		// ✅ 在生成 ldi.xml 时，把 name 的第一段替换成 txt 文件名
-		name := replaceElementPrefixWithTxtName(nn.Path, modelName)
+		path := buildHierNameForNode(n)
+		// ✅ 在生成 ldi.xml 时，把 name 的第一段替换成模型名
+		name := replaceElementPrefixWithModelName(path, modelName)
 
-		el := ldiElement{
-			Name: name,
-			Property: []ldiProperty{
-				{
-					Name:  "coverage.m1",
-					Value: fmt.Sprintf("%.4f", n.Coverage),
-				},
-			},
+		el := doc.AddElement(name)
+		if bottom {
+			writeChartStats(el, n.Chart)
+			continue
+		}
+		el.SetProperty("coverage.m1", fmt.Sprintf("%.4f", n.Coverage))
+		for _, c := range controlOutputs {
+			if cnt := n.ControlPorts[c.control]; cnt > 0 {
+				el.SetProperty(c.property, fmt.Sprintf("%d", cnt))
+			}
+		}
+		if sfComplexity && n.Chart != nil {
+			writeChartStats(el, n.Chart)
+		}
+		if n.SourceBlock != "" {
+			el.AddTag(TagLibrary)
+			el.AddTag(TagLibrary + ":" + n.SourceBlock)
+		} else if n.InLibrary {
+			el.AddTag(TagLibrary)
 		}
-		root.Items = append(root.Items, el)
-	}
-
-	out, err := xml.MarshalIndent(root, "", "  ")
-	if err != nil {
-		return fmt.Errorf("序列化 LDI XML 失败: %v", err)
 	}
 
-	content := append([]byte(xml.Header), out...)
-	if err := os.WriteFile(ldiPath, content, 0644); err != nil {
+	if err := doc.Write(ldiPath); err != nil {
 		return fmt.Errorf("写入 LDI 文件失败: %v", err)
 	}
 	return nil
 }
 
+// chart 的结构统计：状态数、转移数、状态嵌套深度
+func writeChartStats(el *LDI.Element, c *Model_IR.Chart) {
+	el.SetProperty("m1.sf_states", fmt.Sprintf("%d", c.States))
+	el.SetProperty("m1.sf_transitions", fmt.Sprintf("%d", c.Transitions))
+	el.SetProperty("m1.sf_depth", fmt.Sprintf("%d", c.Depth))
+}
+
 // 生成 XXX_m1.txt，总结每个层级节点的：自身端口数、子节点个数、子节点端口总数
-// 仅输出到 maxLevel-1 层
-func writeM1StatsTxt(statsPath string, nodes []*m1Node) error {
+// 开头以 "# " 输出所用的计算式和各层权重；仅输出到 maxLevel-1 层
+func writeM1StatsTxt(statsPath string, nodes []*m1Node, formula Config.M1Formula) error {
 	if len(nodes) == 0 {
 		return nil
 	}
@@ -688,6 +705,12 @@ func writeM1StatsTxt(statsPath string, nodes []*m1Node) error {
 	}
 	defer f.Close()
 
+	for _, line := range formulaLines(formula, maxLevel) {
+		if _, err := f.WriteString("# " + line + "\n"); err != nil {
+			return err
+		}
+	}
+
 	for _, n := range nodes {
 		// 只输出到 N-1 层，最底层不输出
 		if n.Level >= maxLevel {
@@ -695,28 +718,30 @@ func writeM1StatsTxt(statsPath string, nodes []*m1Node) error {
 		}
 		lv := n.Level
 
-		// L1: 端口数带 C-S 权重
+		// L1: 端口数带权重
 		if lv == 1 {
 			line := fmt.Sprintf(
-				"[L1] Name: %s\tL1Ports(Weighted)=%.1f\tL2Count=%d\tL2Ports=%d\n",
+				"[L1] Name: %s\tL1Ports(Weighted)=%.1f\tL2Count=%d\tL2Ports=%s%s\n",
 				n.Name,
 				n.EffectivePorts,
 				n.ChildCount,
-				n.ChildPorts,
+				formatWeight(n.ChildPorts),
+				controlStats(n),
 			)
 			if _, err := f.WriteString(line); err != nil {
 				return err
 			}
 		} else {
-			// L2 及之后：端口不加权，直接用 Ports
+			// L2 及之后：默认权重下等于 Ports
 			nextLevel := lv + 1
 			line := fmt.Sprintf(
-				"[L%d] Name: %s\tL%dPorts=%d\tL%dCount=%d\tL%dPorts=%d\n",
+				"[L%d] Name: %s\tL%dPorts=%s\tL%dCount=%d\tL%dPorts=%s%s\n",
 				lv,
 				n.Name,
-				lv, n.Ports,
+				lv, formatWeight(n.EffectivePorts),
 				nextLevel, n.ChildCount,
-				nextLevel, n.ChildPorts,
+				nextLevel, formatWeight(n.ChildPorts),
+				controlStats(n),
 			)
 			if _, err := f.WriteString(line); err != nil {
 				return err
@@ -726,3 +751,50 @@ func writeM1StatsTxt(statsPath string, nodes []*m1Node) error {
 
 	return nil
 }
+
+// 计算式说明，如
+//
+//	M1 = P × N × C（P：节点自身加权端口数，N：下一层节点数，C：下一层节点 P 之和）
+//	L1 端口权重: trigger=1 enable=1 ... cs=1.2 ... sr=1
+//	L2+ 端口权重: trigger=1 enable=1 ...
+//
+// 只列出到 maxLevel-1 层用到的权重；最后一行的层级带 "+"，表示更深的层级也用它
+func formulaLines(formula Config.M1Formula, maxLevel int) []string {
+	lines := []string{"M1 = P × N × C（P：节点自身加权端口数，N：下一层节点数，C：下一层节点 P 之和）"}
+
+	last := len(formula.PortWeights)
+	if maxLevel < last {
+		last = maxLevel
+	}
+	for lv := 1; lv <= last; lv++ {
+		weights := formula.WeightsFor(lv)
+		parts := make([]string, 0, len(Config.M1PortKinds))
+		for _, kind := range Config.M1PortKinds {
+			parts = append(parts, kind+"="+formatWeight(weights.Weight(kind)))
+		}
+		label := fmt.Sprintf("L%d", lv)
+		if lv == len(formula.PortWeights) && lv < maxLevel {
+			label += "+"
+		}
+		lines = append(lines, label+" 端口权重: "+strings.Join(parts, " "))
+	}
+	return lines
+}
+
+// 加权值去掉多余的 0，如 4 → "4"，1.25 → "1.25"（保留 4 位小数）
+func formatWeight(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 4, 64)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// 控制端口个数，如 "\tTrigger=1\tFunctionCall=2"（没有控制端口时为空）
+func controlStats(n *m1Node) string {
+	var sb strings.Builder
+	for _, c := range controlOutputs {
+		if cnt := n.ControlPorts[c.control]; cnt > 0 {
+			fmt.Fprintf(&sb, "\t%s=%d", c.label, cnt)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,108 @@
+package File_Utils_M1
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"FCU_Tools/M1/M1_Public_Data"
+	"FCU_Tools/Public_data"
+)
+
+func TestMatchAny(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		rel      string
+		want     bool
+	}{
+		// "**" 匹配 0 个或多个文件夹
+		{[]string{"**/*.slx"}, "A.slx", true},
+		{[]string{"**/*.slx"}, "a/b/A.slx", true},
+		{[]string{"**/*.slx"}, "a/b/A.mdl", false},
+		{[]string{"src/**/*.slx"}, "src/A.slx", true},
+		{[]string{"src/**/*.slx"}, "src/x/y/A.slx", true},
+		{[]string{"src/**/*.slx"}, "other/A.slx", false},
+		{[]string{"**/test/**"}, "a/test", true},
+		{[]string{"**/test/**"}, "a/test/b/A.slx", true},
+		{[]string{"**/test/**"}, "a/testing/A.slx", false},
+		{[]string{"a/**/b"}, "a/b", true},
+		// 一个 "*" 不跨文件夹
+		{[]string{"*/*.slx"}, "a/A.slx", true},
+		{[]string{"*/*.slx"}, "a/b/A.slx", false},
+		// 没有 "/" 的模式只和文件名比较，任何文件夹中都匹配
+		{[]string{"*_harness.slx"}, "a/b/M_harness.slx", true},
+		{[]string{"*_harness.slx"}, "M_harness.slx", true},
+		{[]string{"*_harness.slx"}, "a_harness.slx/M.slx", false},
+		{[]string{"test"}, "a/test", true},
+		// 任意一个匹配即可
+		{[]string{"*.mdl", "**/*.slx"}, "x/A.slx", true},
+		{nil, "A.slx", false},
+	}
+	for _, tt := range tests {
+		if got := matchAny(tt.patterns, tt.rel); got != tt.want {
+			t.Errorf("matchAny(%q, %q) = %v，期望 %v", tt.patterns, tt.rel, got, tt.want)
+		}
+	}
+}
+
+func TestDiscoverModels(t *testing.T) {
+	root := t.TempDir()
+	for _, f := range []string{
+		"a/X.mdl", "a/X.slx", // 同一文件夹同名：只取 slx
+		"a/Y.mdl", // 没有 slx 时取 mdl
+		"b/Z.slx",
+		"b/Z_harness.slx",    // 按文件名排除
+		"b/test/T.slx",       // 排除的文件夹整个跳过
+		"c/x.SLX", "c/X.mdl", // 扩展名不区分大小写；与 a/X.slx 模型名重复（不同文件夹）
+		"c/notes.txt",
+	} {
+		p := filepath.Join(root, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ws := &M1_Public_Data.Workspace{
+		SrcPath: root,
+		Include: []string{"**/*.slx", "**/*.mdl"},
+		Exclude: []string{"**/test/**", "*_harness.slx"},
+	}
+	start := Public_data.WarningCount()
+	got, err := FindSlxModels(ws)
+	if err != nil {
+		t.Fatalf("FindSlxModels: %v", err)
+	}
+	var rel []string
+	for _, p := range got {
+		r, _ := filepath.Rel(root, p)
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	if want := []string{"a/X.slx", "a/Y.mdl", "b/Z.slx"}; !reflect.DeepEqual(rel, want) {
+		t.Errorf("模型 = %v，期望 %v", rel, want)
+	}
+	// 只有 c/x.SLX 与 a/X.slx 跨文件夹重名时警告；同一文件夹的 X.mdl / X.slx 不警告
+	if n := Public_data.WarningCount() - start; n != 1 {
+		t.Errorf("警告 %d 个，期望 1 个", n)
+	}
+}
+
+func TestFindSlxModelsErrors(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.txt")
+	if err := os.WriteFile(empty, []byte("# 没有模型\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for name, ws := range map[string]*M1_Public_Data.Workspace{
+		"列表不存在":    {ModelList: filepath.Join(dir, "nope.txt")},
+		"列表中没有模型":  {ModelList: empty},
+		"文件夹中没有模型": {SrcPath: dir},
+	} {
+		if _, err := FindSlxModels(ws); err == nil {
+			t.Errorf("%s：期望出错", name)
+		}
+	}
+}
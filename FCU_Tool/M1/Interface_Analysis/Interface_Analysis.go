@@ -0,0 +1,348 @@
+package Interface_Analysis
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"FCU_Tools/M1/SLX_Reader"
+	"FCU_Tools/M1/System_Tree"
+)
+
+// 对外暴露的模型根层接口（simulink/graphicalInterface.xml）
+//   - Inports / Outports : 根层输入输出端口（按文件内顺序，Port 为端口序号）
+//   - Provides / Requires : Client-Server 函数（ProvideFunction / RequireFunction）
+//   - DataStores          : 模型引用的全局 Data Store（DataStoreReference）
+//   - Declared            : 文件中声明的个数（NumRootInports 等 → 值），用于检查
+type Interface struct {
+	Inports    []RootPort
+	Outports   []RootPort
+	Provides   []string
+	Requires   []string
+	DataStores []string
+	Declared   map[string]int
+}
+
+// RootPort 是根层的一个 Inport / Outport
+//   - BusObject    : 总线对象名（不是总线时为空）
+//   - FunctionCall : OutputFunctionCall=on 的 function-call 输入
+//   - SampleTime   : 采样时间（原样保留，如 "-1"）
+type RootPort struct {
+	Name         string
+	Port         int
+	BusObject    string
+	FunctionCall bool
+	SampleTime   string
+}
+
+// BusObjects 返回根层端口用到的总线对象（去重，按出现顺序）
+func (i *Interface) BusObjects() []string {
+	var out []string
+	for _, p := range slices.Concat(i.Inports, i.Outports) {
+		if p.BusObject != "" && !slices.Contains(out, p.BusObject) {
+			out = append(out, p.BusObject)
+		}
+	}
+	return out
+}
+
+// FunctionCallInputs 返回 function-call 输入端口名
+func (i *Interface) FunctionCallInputs() []string {
+	var out []string
+	for _, p := range i.Inports {
+		if p.FunctionCall {
+			out = append(out, p.Name)
+		}
+	}
+	return out
+}
+
+// 内部 XML 结构：graphicalInterface.xml 各元素的名字有的在 Name 属性中，有的在 <P Name="Name"> 中，统一按通用节点读入
+type xmlP struct {
+	Name  string `xml:"Name,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Ps      []xmlP     `xml:"P"`
+	Nodes   []xmlNode  `xml:",any"`
+}
+
+func (n *xmlNode) prop(name string) string {
+	for _, p := range n.Ps {
+		if p.Name == name {
+			return strings.TrimSpace(p.Value)
+		}
+	}
+	return ""
+}
+
+func (n *xmlNode) name() string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == "Name" {
+			return normalizeName(a.Value)
+		}
+	}
+	for _, key := range []string{"Name", "DataStoreName"} {
+		if v := n.prop(key); v != "" {
+			return normalizeName(v)
+		}
+	}
+	return ""
+}
+
+// 文件中声明个数的 P，和对应的列表
+var declaredCounts = []struct {
+	key  string
+	list func(*Interface) int
+}{
+	{"NumRootInports", func(i *Interface) int { return len(i.Inports) }},
+	{"NumRootOutports", func(i *Interface) int { return len(i.Outports) }},
+	{"NumProvidedFunctions", func(i *Interface) int { return len(i.Provides) }},
+	{"NumRequiredFunctions", func(i *Interface) int { return len(i.Requires) }},
+	{"NumDataStoreReferences", func(i *Interface) int { return len(i.DataStores) }},
+}
+
+// 从 slx 内的 simulink/graphicalInterface.xml 中解析根层接口
+func GetInterface(fsys fs.FS) (*Interface, error) {
+	iface := &Interface{Declared: make(map[string]int)}
+
+	if fsys == nil {
+		return iface, nil
+	}
+
+	giPath := SLX_Reader.GraphicalInterface
+
+	data, err := fs.ReadFile(fsys, giPath)
+	if err != nil {
+		return iface, fmt.Errorf("读取 graphicalInterface.xml 失败 [%s]: %w", giPath, err)
+	}
+
+	var root xmlNode
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return iface, fmt.Errorf("解析 graphicalInterface.xml 失败 [%s]: %w", giPath, err)
+	}
+
+	for _, p := range root.Ps {
+		for _, c := range declaredCounts {
+			if p.Name != c.key {
+				continue
+			}
+			if n, err := strconv.Atoi(strings.TrimSpace(p.Value)); err == nil {
+				iface.Declared[c.key] = n
+			}
+		}
+	}
+
+	for _, n := range root.Nodes {
+		name := n.name()
+		if name == "" {
+			continue
+		}
+		switch n.XMLName.Local {
+		case "Inport":
+			iface.Inports = append(iface.Inports, rootPort(&n, name, len(iface.Inports)+1))
+		case "Outport":
+			iface.Outports = append(iface.Outports, rootPort(&n, name, len(iface.Outports)+1))
+		case "ProvideFunction":
+			iface.Provides = append(iface.Provides, name)
+		case "RequireFunction":
+			iface.Requires = append(iface.Requires, name)
+		case "DataStoreReference":
+			iface.DataStores = append(iface.DataStores, name)
+		}
+	}
+	return iface, nil
+}
+
+func rootPort(n *xmlNode, name string, port int) RootPort {
+	return RootPort{
+		Name:         name,
+		Port:         port,
+		BusObject:    busName(n.prop("BusObject")),
+		FunctionCall: n.prop("OutputFunctionCall") == "on",
+		SampleTime:   n.prop("SampleTime"),
+	}
+}
+
+// ======================== 与 system_root.xml 对照 ========================
+
+// CrossCheck 把 graphicalInterface.xml 的接口与系统树对照，返回不一致的地方（没有时为空）
+//   - 声明的个数（NumRootInports 等）与实际列出的个数
+//   - 根层 Inport / Outport：按端口序号对照名字、function-call、总线对象、采样时间
+//   - Data Store：模型中找不到 Data Store Memory 的 Data Store Read / Write（全局 Data Store）与 DataStoreReference
+func CrossCheck(iface *Interface, model *System_Tree.Model) []string {
+	var out []string
+	for _, c := range declaredCounts {
+		if n, ok := iface.Declared[c.key]; ok && n != c.list(iface) {
+			out = append(out, fmt.Sprintf("graphicalInterface.xml 声明 %s=%d，实际列出 %d 个", c.key, n, c.list(iface)))
+		}
+	}
+	if model == nil || model.Root == nil {
+		return out
+	}
+	out = append(out, checkPorts("Inport", iface.Inports, rootBlocks(model.Root, "Inport"))...)
+	out = append(out, checkPorts("Outport", iface.Outports, rootBlocks(model.Root, "Outport"))...)
+	out = append(out, checkDataStores(iface.DataStores, globalDataStores(model.Root))...)
+	return out
+}
+
+// rootBlocks 返回 system_root 中的 Inport / Outport Block，按端口序号（Port，默认 1）
+// 总线元素端口（In Bus Element / Out Bus Element）多个 Block 共用一个端口，只取第一个
+func rootBlocks(root *System_Tree.System, blockType string) map[int]*System_Tree.Block {
+	out := make(map[int]*System_Tree.Block)
+	for _, b := range root.Blocks {
+		if b.BlockType != blockType {
+			continue
+		}
+		port := 1
+		if v := b.Prop("Port"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				continue
+			}
+			port = n
+		}
+		if _, ok := out[port]; !ok {
+			out[port] = b
+		}
+	}
+	return out
+}
+
+// 根层端口在 system_root 中的名字（总线元素端口为 PortName）
+func blockPortName(b *System_Tree.Block) string {
+	if b.Prop("IsBusElementPort") == "on" && b.Prop("PortName") != "" {
+		return normalizeName(b.Prop("PortName"))
+	}
+	return normalizeName(b.Name)
+}
+
+// 根层端口 Block 的总线对象：OutDataTypeStr 为 "Bus: X"，或旧版本的 UseBusObject=on + BusObject=X
+func blockBusObject(b *System_Tree.Block) string {
+	if b.Prop("UseBusObject") == "on" {
+		return busName(b.Prop("BusObject"))
+	}
+	if v, ok := strings.CutPrefix(b.Prop("OutDataTypeStr"), "Bus:"); ok {
+		return busName(v)
+	}
+	return ""
+}
+
+func checkPorts(kind string, ports []RootPort, blocks map[int]*System_Tree.Block) []string {
+	var out []string
+	seen := make(map[int]bool)
+	for _, p := range ports {
+		seen[p.Port] = true
+		b, ok := blocks[p.Port]
+		if !ok {
+			out = append(out, fmt.Sprintf("根层 %s %d（%s）只在 graphicalInterface.xml 中", kind, p.Port, p.Name))
+			continue
+		}
+		if name := blockPortName(b); name != p.Name {
+			out = append(out, fmt.Sprintf("根层 %s %d 名字不一致：graphicalInterface.xml 为 %s，system_root.xml 为 %s", kind, p.Port, p.Name, name))
+		}
+		if fc := b.Prop("OutputFunctionCall") == "on"; kind == "Inport" && fc != p.FunctionCall {
+			out = append(out, fmt.Sprintf("根层 %s %s 的 function-call 设置不一致：graphicalInterface.xml 为 %s，system_root.xml 为 %s",
+				kind, p.Name, onOff(p.FunctionCall), onOff(fc)))
+		}
+		if bus := blockBusObject(b); bus != p.BusObject {
+			out = append(out, fmt.Sprintf("根层 %s %s 的总线对象不一致：graphicalInterface.xml 为 %q，system_root.xml 为 %q", kind, p.Name, p.BusObject, bus))
+		}
+		// system_root.xml 中省略默认值 -1
+		st := b.Prop("SampleTime")
+		if st == "" {
+			st = "-1"
+		}
+		if p.SampleTime != "" && st != p.SampleTime {
+			out = append(out, fmt.Sprintf("根层 %s %s 的采样时间不一致：graphicalInterface.xml 为 %s，system_root.xml 为 %s", kind, p.Name, p.SampleTime, st))
+		}
+	}
+	for _, port := range slices.Sorted(maps.Keys(blocks)) {
+		if !seen[port] {
+			out = append(out, fmt.Sprintf("根层 %s %d（%s）只在 system_root.xml 中", kind, port, blockPortName(blocks[port])))
+		}
+	}
+	return out
+}
+
+// globalDataStores 返回模型中引用、但在所在 system 及其上层都找不到 Data Store Memory 的 Data Store 名（按出现顺序）
+func globalDataStores(root *System_Tree.System) []string {
+	var out []string
+	visited := make(map[*System_Tree.System]bool)
+	var walk func(sys *System_Tree.System)
+	walk = func(sys *System_Tree.System) {
+		if sys == nil || visited[sys] {
+			return
+		}
+		visited[sys] = true
+		for _, b := range sys.Blocks {
+			if b.BlockType != "DataStoreRead" && b.BlockType != "DataStoreWrite" {
+				continue
+			}
+			name := b.Prop("DataStoreName")
+			if name != "" && !hasMemory(sys, name) && !slices.Contains(out, name) {
+				out = append(out, name)
+			}
+		}
+		for _, b := range sys.Blocks {
+			walk(b.Child)
+		}
+	}
+	walk(root)
+	return out
+}
+
+func hasMemory(sys *System_Tree.System, name string) bool {
+	for s := sys; s != nil; s = s.Parent {
+		for _, b := range s.Blocks {
+			if b.BlockType == "DataStoreMemory" && b.Prop("DataStoreName") == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func checkDataStores(declared, used []string) []string {
+	var out []string
+	for _, name := range declared {
+		if !slices.Contains(used, name) {
+			out = append(out, fmt.Sprintf("Data Store %s 只在 graphicalInterface.xml 中（模型中没有读写）", name))
+		}
+	}
+	for _, name := range used {
+		if !slices.Contains(declared, name) {
+			out = append(out, fmt.Sprintf("Data Store %s 在模型中读写，但不在 graphicalInterface.xml 中", name))
+		}
+	}
+	return out
+}
+
+// "Bus: X" / " X " → "X"
+func busName(s string) string {
+	s, _ = strings.CutPrefix(strings.TrimSpace(s), "Bus:")
+	return strings.TrimSpace(s)
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+// 把名字里的换行 / 多余空白压成一个空格
+func normalizeName(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return s
+	}
+	return strings.Join(strings.Fields(s), " ")
+}
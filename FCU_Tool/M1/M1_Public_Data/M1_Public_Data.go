@@ -4,51 +4,78 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"FCU_Tools/Config"
+	"FCU_Tools/M1/SLX_Reader"
 )
 
-var (
+// Workspace 保存 M1 的工作目录（由 Config.WorkDir 推出）和模型输入路径，
+// 由 M1main 创建后显式传给各个分析步骤
+type Workspace struct {
 	WorkDir   string
 	M1Dir     string
 	BuildDir  string
 	OutputDir string
 	LDIDir    string
 	TxtDir    string
+	IRDir     string // 每个模型的中间表示 <Model>.json
 
-	SrcPath   string // ← 这里用来保存你输入的 Windows 路径
-)
+	SrcPath     string   // 模型根目录（Config.Inputs.ModelsDir）
+	Include     []string // 在 SrcPath 下递归查找模型的 glob（Config.Inputs.ModelInclude），为空时按 <Folder>/<Folder>.slx 查找
+	Exclude     []string // 排除的 glob（Config.Inputs.ModelExclude）
+	ModelList   string   // 模型列表文件（Config.Inputs.ModelList），可为空
+	LibraryDirs []string // 库 slx 所在目录（Config.Inputs.LibraryDirs），库链接到这里找库
 
-func SetWorkDir() {
-	wd, err := os.Getwd()
-	if err != nil {
-		fmt.Println("❌ 获取当前工作目录失败:", err)
-		return
-	}
-	WorkDir = wd
+	Limits SLX_Reader.Limits // 读取 / 解压 slx 的大小上限（metrics.m1.max_entry_mb / max_total_mb）
+	Jobs   int               // 并行分析的模型数（jobs / --jobs，0 为 CPU 数）
 
-	M1Dir = filepath.Join(WorkDir, "M1")
-	BuildDir = filepath.Join(M1Dir, "build")
-	OutputDir = filepath.Join(M1Dir, "output")
-	LDIDir = filepath.Join(OutputDir, "LDI")
-	TxtDir = filepath.Join(OutputDir, "txt")
+	WriteTxt bool // 是否另外写出 <Model>.txt（metrics.m1.write_txt，仅供人工查看）
+
+	Options Config.M1Options // metrics.m1：分析深度（全局 / 按模型）、各层 Block 选择规则
+}
 
-	removeIfExists(BuildDir)
-	removeIfExists(OutputDir)
+// NewWorkspace 创建工作空间：M1/output/LDI、M1/output/txt、M1/output/ir（已存在则先清空）
+// M1/build 只在 metrics.m1.extract 打开时由复制步骤创建，这里只清掉上次留下的内容
+func NewWorkspace(cfg *Config.Config) (*Workspace, error) {
+	ws := newPaths(cfg)
 
-	dirs := []string{M1Dir, BuildDir, LDIDir, TxtDir}
+	removeIfExists(ws.BuildDir)
+	removeIfExists(ws.OutputDir)
+
+	dirs := []string{ws.M1Dir, ws.LDIDir, ws.TxtDir, ws.IRDir}
 	for _, d := range dirs {
 		if err := os.MkdirAll(d, 0755); err != nil {
-			fmt.Printf("❌ 创建目录失败 [%s]: %v\n", d, err)
-			return
+			return nil, fmt.Errorf("创建目录失败 [%s]: %v", d, err)
 		}
 	}
 
 	fmt.Println("✅ M1 工作空间初始化成功")
-	// fmt.Println("    WorkDir  :", WorkDir)
-	// fmt.Println("    M1Dir    :", M1Dir)
-	// fmt.Println("    BuildDir :", BuildDir)
-	// fmt.Println("    OutputDir:", OutputDir)
-	// fmt.Println("    LDIDir   :", LDIDir)
-	// fmt.Println("    TxtDir   :", TxtDir)
+	return ws, nil
+}
+
+func newPaths(cfg *Config.Config) *Workspace {
+	ws := &Workspace{
+		WorkDir:     cfg.WorkDir,
+		SrcPath:     cfg.Inputs.ModelsDir,
+		Include:     cfg.Inputs.ModelInclude,
+		Exclude:     cfg.Inputs.ModelExclude,
+		ModelList:   cfg.Inputs.ModelList,
+		LibraryDirs: cfg.Inputs.LibraryDirs,
+		Limits: SLX_Reader.Limits{
+			MaxEntrySize: cfg.Metrics.M1.MaxEntryMB << 20,
+			MaxTotalSize: cfg.Metrics.M1.MaxTotalMB << 20,
+		},
+		Jobs:     cfg.Jobs,
+		WriteTxt: cfg.Metrics.M1.WriteTxt,
+		Options:  cfg.Metrics.M1,
+	}
+	ws.M1Dir = filepath.Join(ws.WorkDir, "M1")
+	ws.BuildDir = filepath.Join(ws.M1Dir, "build")
+	ws.OutputDir = filepath.Join(ws.M1Dir, "output")
+	ws.LDIDir = filepath.Join(ws.OutputDir, "LDI")
+	ws.TxtDir = filepath.Join(ws.OutputDir, "txt")
+	ws.IRDir = filepath.Join(ws.OutputDir, "ir")
+	return ws
 }
 
 func removeIfExists(path string) {
@@ -1,31 +1,84 @@
 package M1main
 
 import (
+	"FCU_Tools/Config"
+	"FCU_Tools/Dependency_Graph"
+	"FCU_Tools/LDI"
 	"FCU_Tools/M1/M1_Public_Data"
 	"FCU_Tools/M1/File_Utils_M1"
 	"FCU_Tools/M1/Analysis_Process"
 	"FCU_Tools/M1/LDI_M1_Create"
+	"FCU_Tools/Metric"
+	"FCU_Tools/Run_Manifest"
 )
 
-func M1_main() {
-	// 1. 创建工作空间：M1/Build、M1/Output/LDI、M1/Output/txt
-	M1_Public_Data.SetWorkDir()
+func init() {
+	Metric.Register(m1{})
+}
+
+// m1 是 M1（模型层级端口复杂度）指标，在 init 中注册到 Metric 注册表
+type m1 struct{}
+
+func (m1) Name() string { return "M1" }
+
+func (m1) Inputs() []*Metric.Input {
+	return []*Metric.Input{Metric.AswCsv, Metric.Models, Metric.ModelList}
+}
+
+func (m1) Enabled(cfg *Config.Config) bool { return cfg.Metrics.M1.Enabled }
+
+func (m1) Compute(ctx *Metric.Context, model *Dependency_Graph.Graph) (*LDI.Document, error) {
+	return M1_compute(ctx.Config, ctx.Manifest)
+}
+
+// M1_compute 计算 M1：生成 M1/output/ir/*.json、M1/output/LDI/*.ldi.xml，并返回按模型名汇总的 coverage.m1（不修改主 LDI）
+// 模型根目录、分析深度都来自 cfg（配置文件 / 命令行）；实际分析的模型文件记录到 rec（run.json，可为 nil）
+func M1_compute(cfg *Config.Config, rec *Run_Manifest.Manifest) (*LDI.Document, error) {
+	// 1. 创建工作空间：M1/Output/LDI、M1/Output/txt、M1/Output/ir
+	ws, err := M1_Public_Data.NewWorkspace(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	// 2. 读取 Windows 路径
-	File_Utils_M1.ReadWindowsPath()
-	
-	// 3. 复制符合要求的 slx 文件到 BuildDir
-	File_Utils_M1.CopySlxToBuild()
+	// 2. 找出 <Folder>/<Folder>.slx 模型文件（直接从 zip 读取，不再复制、解压；没有 slx 时取同名 .mdl，转换成相同的结构）
+	//    模型列表读不了、或一个模型也没有找到时 M1 失败
+	slxPaths, err := File_Utils_M1.FindSlxModels(ws)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range slxPaths {
+		if err := rec.AddInput("SLX 모델", p); err != nil {
+			return nil, err
+		}
+	}
 
-	// 4. 解压 slx 文件到 BuildDir 下同名目录
-	File_Utils_M1.UnzipSlxFiles()
+	// 3. 需要查看 xml 时（metrics.m1.extract），另外复制并解压到 BuildDir
+	//    不安全的 slx（zip-slip、符号链接、超过 max_entry_mb / max_total_mb）会让 M1 失败
+	if cfg.Metrics.M1.Extract {
+		File_Utils_M1.CopySlxToBuild(ws, slxPaths)
+		if err := File_Utils_M1.UnzipSlxFiles(ws); err != nil {
+			return nil, err
+		}
+	}
 
-	// 5. 分析流程设定，参数决定分析的深度，但是只测试到第三层，因为目前的需求是前三层的内容
-	Analysis_Process.RunAnalysis(3)
+	// 4. 分析流程：深度取 metrics.m1.depth / model_depth（默认 3 层，可为 auto），各层选择规则取 metrics.m1.levels
+	//    结果为每个模型的中间表示，同时写出 M1/output/ir/<Model>.json
+	//    库链接（Reference）到 inputs.library_dirs 下的库 slx 中展开
+	//    库文件展开后会改变 coverage.m1，同样记录到 rec
+	libPaths := File_Utils_M1.FindLibraries(ws)
+	for _, p := range libPaths {
+		if err := rec.AddInput("라이브러리", p); err != nil {
+			return nil, err
+		}
+	}
+	models, err := Analysis_Process.RunAnalysis(ws, slxPaths, libPaths)
+	if err != nil {
+		return nil, err
+	}
 
-	// 6. 根据txt文件生成ldi.xml文件
-	File_Utils_M1.GenerateM1LDIFromTxt()
+	// 5. 根据中间表示生成ldi.xml文件
+	File_Utils_M1.GenerateM1LDI(ws, models)
 
-	// 7. 将M1的ldi.xml合并到主ldi.xml
-	LDI_M1_Create.MergeM1ToMainLDI()
+	// 6. 按 asw.csv 把 runnable 名换成模型名，汇总 coverage.m1
+	return LDI_M1_Create.LoadM1PartialLDI(cfg, ws)
 }
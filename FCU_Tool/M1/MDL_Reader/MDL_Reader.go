@@ -0,0 +1,474 @@
+package MDL_Reader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"FCU_Tools/M1/SLX_Reader"
+)
+
+// Ext 是文本格式模型的扩展名
+const Ext = ".mdl"
+
+// IsMDL 判断模型文件是否为文本格式（.mdl）
+func IsMDL(modelPath string) bool {
+	return strings.EqualFold(filepath.Ext(modelPath), Ext)
+}
+
+// Open 读取 .mdl 文本模型，转换成与 slx 相同的文件结构，返回的 Model 与 SLX_Reader.Open 的结果用法相同
+//   - Model { System {...} } / Library { System {...} } → simulink/systems/system_root.xml，
+//     带 System 的 Block → simulink/systems/system_<SID>.xml
+//   - Line 的 SrcBlock/SrcPort、DstBlock/DstPort（按 Block 名）→ <P Name="Src">SID#out:1</P> 等，嵌套的 Branch 展平
+//   - Ports [in, out, enable, trigger, ...] → <PortCounts>；其他 Block 参数 → <P>
+//   - GraphicalInterface { ... } → simulink/graphicalInterface.xml
+//   - Stateflow { machine / chart / state / transition / data / event ... } → simulink/stateflow/machine.xml（按 treeNode / linkNode 组成层次）
+//
+// 没有 SID 的旧版本 Block 从模型中最大的 SID 往后编号；文件大小受 lim.MaxTotalSize 限制
+func Open(mdlPath string, lim SLX_Reader.Limits) (*SLX_Reader.Model, error) {
+	info, err := os.Stat(mdlPath)
+	if err != nil {
+		return nil, fmt.Errorf("打开 mdl 失败 [%s]: %w", mdlPath, err)
+	}
+	if info.Size() > lim.MaxTotalSize {
+		return nil, &SLX_Reader.ArchiveError{Archive: mdlPath, Entry: filepath.Base(mdlPath), Err: SLX_Reader.ErrTotalTooLarge,
+			Detail: fmt.Sprintf("上限 %d 字节", lim.MaxTotalSize)}
+	}
+	data, err := os.ReadFile(mdlPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取 mdl 失败 [%s]: %w", mdlPath, err)
+	}
+
+	root, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析 mdl 失败 [%s]: %w", mdlPath, err)
+	}
+
+	c := &converter{files: make(map[string][]byte)}
+	if err := c.convert(root); err != nil {
+		return nil, fmt.Errorf("转换 mdl 失败 [%s]: %w", mdlPath, err)
+	}
+
+	base := filepath.Base(mdlPath)
+	return SLX_Reader.NewModel(strings.TrimSuffix(base, filepath.Ext(base)), mdlPath, c.files, info.ModTime()), nil
+}
+
+// ======================== 文本解析 ========================
+
+// node 是 mdl 中的一个段落 "Kind { ... }"
+type node struct {
+	Kind     string
+	Params   []param
+	Children []*node
+}
+
+type param struct {
+	Key   string
+	Value string
+}
+
+func (n *node) get(key string) string {
+	for _, p := range n.Params {
+		if p.Key == key {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+func (n *node) has(key string) bool {
+	for _, p := range n.Params {
+		if p.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *node) children(kind string) []*node {
+	var out []*node
+	for _, c := range n.Children {
+		if c.Kind == kind {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// parse 把 mdl 文本解析成段落树（返回的根节点 Kind 为空）
+// 每行为 "Kind {"、"}"、"Key 值" 之一；值为字符串时，下一行开头的字符串是它的续行
+func parse(data []byte) (*node, error) {
+	root := &node{}
+	stack := []*node{root}
+	var last *param // 最近一个字符串参数，用于拼接续行
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		cur := stack[len(stack)-1]
+
+		switch {
+		case line == "}":
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("第 %d 行：多余的 \"}\"", lineNo)
+			}
+			stack = stack[:len(stack)-1]
+			last = nil
+		case strings.HasSuffix(line, "{") && !strings.HasPrefix(line, "\""):
+			n := &node{Kind: strings.TrimSpace(strings.TrimSuffix(line, "{"))}
+			cur.Children = append(cur.Children, n)
+			stack = append(stack, n)
+			last = nil
+		case strings.HasPrefix(line, "\""):
+			s, err := unquote(line)
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 行：%v", lineNo, err)
+			}
+			if last == nil {
+				return nil, fmt.Errorf("第 %d 行：字符串前没有参数名", lineNo)
+			}
+			last.Value += s
+		default:
+			key, value := line, ""
+			if i := strings.IndexAny(line, " \t"); i >= 0 {
+				key, value = line[:i], strings.TrimSpace(line[i:])
+			}
+			quoted := strings.HasPrefix(value, "\"")
+			if quoted {
+				s, err := unquote(value)
+				if err != nil {
+					return nil, fmt.Errorf("第 %d 行：%v", lineNo, err)
+				}
+				value = s
+			}
+			cur.Params = append(cur.Params, param{Key: key, Value: value})
+			last = nil
+			if quoted {
+				last = &cur.Params[len(cur.Params)-1]
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(stack) != 1 {
+		return nil, fmt.Errorf("段落 %q 没有结束", stack[len(stack)-1].Kind)
+	}
+	return root, nil
+}
+
+// unquote 解析 "..." 字符串（\" \\ \n \t 转义），后面不能再有其他内容
+func unquote(s string) (string, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			if strings.TrimSpace(s[i+1:]) != "" {
+				return "", fmt.Errorf("字符串后有多余内容：%s", s)
+			}
+			return b.String(), nil
+		case '\\':
+			i++
+			if i >= len(s) {
+				return "", fmt.Errorf("字符串没有结束：%s", s)
+			}
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("字符串没有结束：%s", s)
+}
+
+// ======================== 转换成 slx 的 xml ========================
+
+// 内部 XML 结构，与 slx 中 simulink/systems/*.xml 相同
+type xmlP struct {
+	Name  string `xml:"Name,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlPortCounts struct {
+	In      string `xml:"in,attr,omitempty"`
+	Out     string `xml:"out,attr,omitempty"`
+	Trigger string `xml:"trigger,attr,omitempty"`
+}
+
+type xmlBlock struct {
+	BlockType  string         `xml:"BlockType,attr"`
+	Name       string         `xml:"Name,attr"`
+	SID        string         `xml:"SID,attr"`
+	PortCounts *xmlPortCounts `xml:"PortCounts"`
+	Ps         []xmlP         `xml:"P"`
+}
+
+type xmlBranch struct {
+	Ps []xmlP `xml:"P"`
+}
+
+type xmlLine struct {
+	Ps       []xmlP      `xml:"P"`
+	Branches []xmlBranch `xml:"Branch"`
+}
+
+type xmlSystem struct {
+	XMLName xml.Name   `xml:"System"`
+	Blocks  []xmlBlock `xml:"Block"`
+	Lines   []xmlLine  `xml:"Line"`
+}
+
+type converter struct {
+	files   map[string][]byte
+	nextSID int
+}
+
+// Block 中不作为 <P> 输出的参数
+var blockAttrs = map[string]bool{"BlockType": true, "Name": true, "SID": true, "Ports": true}
+
+func (c *converter) convert(root *node) error {
+	var model *node
+	for _, kind := range []string{"Model", "Library"} {
+		if ms := root.children(kind); len(ms) > 0 {
+			model = ms[0]
+			break
+		}
+	}
+	if model == nil {
+		return fmt.Errorf("没有 Model / Library 段落")
+	}
+
+	c.nextSID = maxSID(model) + 1
+	if systems := model.children("System"); len(systems) > 0 {
+		if err := c.system(systems[0], "system_root.xml"); err != nil {
+			return err
+		}
+	}
+	for _, gi := range model.children("GraphicalInterface") {
+		if err := c.write(SLX_Reader.GraphicalInterface, generic(gi)); err != nil {
+			return err
+		}
+	}
+	for _, sf := range root.children("Stateflow") {
+		if err := c.write(path.Join(SLX_Reader.StateflowDir, "machine.xml"), stateflow(sf)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxSID 返回模型中最大的数字 SID
+func maxSID(n *node) int {
+	max := 0
+	if v, err := strconv.Atoi(n.get("SID")); err == nil && v > max {
+		max = v
+	}
+	for _, c := range n.Children {
+		if v := maxSID(c); v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+// system 把一个 System 段落写成 simulink/systems/<file>，带 System 的 Block 写成 system_<SID>.xml
+func (c *converter) system(sys *node, file string) error {
+	out := xmlSystem{}
+	sids := make(map[string]string) // Block 名 → SID，用于 Line
+
+	for _, b := range sys.children("Block") {
+		sid := b.get("SID")
+		if sid == "" {
+			sid = strconv.Itoa(c.nextSID)
+			c.nextSID++
+		}
+		sids[b.get("Name")] = sid
+
+		blk := xmlBlock{BlockType: b.get("BlockType"), Name: b.get("Name"), SID: sid}
+		if b.has("Ports") {
+			blk.PortCounts = portCounts(b.get("Ports"))
+		}
+		for _, p := range b.Params {
+			if !blockAttrs[p.Key] {
+				blk.Ps = append(blk.Ps, xmlP{Name: p.Key, Value: p.Value})
+			}
+		}
+		out.Blocks = append(out.Blocks, blk)
+
+		if child := b.children("System"); len(child) > 0 {
+			if err := c.system(child[0], fmt.Sprintf("system_%s.xml", sid)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, l := range sys.children("Line") {
+		line := xmlLine{}
+		if src := endpoint(l, "Src", sids); src != "" {
+			line.Ps = append(line.Ps, xmlP{Name: "Src", Value: src})
+		}
+		if name := l.get("Name"); name != "" {
+			line.Ps = append(line.Ps, xmlP{Name: "Name", Value: name})
+		}
+		if dst := endpoint(l, "Dst", sids); dst != "" {
+			line.Ps = append(line.Ps, xmlP{Name: "Dst", Value: dst})
+		}
+		line.Branches = branches(l, sids, line.Branches)
+		out.Lines = append(out.Lines, line)
+	}
+
+	return c.write(path.Join(SLX_Reader.SystemsDir, file), out)
+}
+
+// branches 把（可能嵌套的）Branch 展平，每个有 Dst 的 Branch 一条
+func branches(n *node, sids map[string]string, out []xmlBranch) []xmlBranch {
+	for _, br := range n.children("Branch") {
+		if dst := endpoint(br, "Dst", sids); dst != "" {
+			out = append(out, xmlBranch{Ps: []xmlP{{Name: "Dst", Value: dst}}})
+		}
+		out = branches(br, sids, out)
+	}
+	return out
+}
+
+// endpoint 把 SrcBlock/SrcPort 或 DstBlock/DstPort 转成 slx 的写法，如 "12#out:1" / "15#trigger"
+// 已经是 slx 写法的 Src / Dst 原样返回
+func endpoint(n *node, side string, sids map[string]string) string {
+	if v := n.get(side); v != "" {
+		return v
+	}
+	name := n.get(side + "Block")
+	if name == "" {
+		return ""
+	}
+	sid, ok := sids[name]
+	if !ok {
+		return ""
+	}
+	port := n.get(side + "Port")
+	if _, err := strconv.Atoi(port); err == nil {
+		dir := "in"
+		if side == "Src" {
+			dir = "out"
+		}
+		return fmt.Sprintf("%s#%s:%s", sid, dir, port)
+	}
+	return sid + "#" + port
+}
+
+// portCounts 解析 "Ports [1, 1, 0, 1]"：输入、输出、enable、trigger、...
+func portCounts(v string) *xmlPortCounts {
+	fields := strings.FieldsFunc(strings.Trim(v, "[] "), func(r rune) bool { return r == ',' || r == ' ' })
+	count := func(i int) string {
+		if i < len(fields) && fields[i] != "0" {
+			return fields[i]
+		}
+		return ""
+	}
+	return &xmlPortCounts{In: count(0), Out: count(1), Trigger: count(3)}
+}
+
+func (c *converter) write(name string, v any) error {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("生成 %s 失败: %w", name, err)
+	}
+	c.files[name] = append([]byte(xml.Header), data...)
+	return nil
+}
+
+// ======================== GraphicalInterface / Stateflow ========================
+
+// xmlNode 是通用的 xml 元素：参数写成 <P Name="...">，子段落写成同名子元素
+type xmlNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Ps       []xmlP     `xml:"P"`
+	Children []*xmlNode `xml:",any"`
+}
+
+func generic(n *node) *xmlNode {
+	x := &xmlNode{XMLName: xml.Name{Local: n.Kind}}
+	for _, p := range n.Params {
+		x.Ps = append(x.Ps, xmlP{Name: p.Key, Value: p.Value})
+	}
+	for _, c := range n.Children {
+		x.Children = append(x.Children, generic(c))
+	}
+	return x
+}
+
+// stateflow 把 mdl 中平铺的 Stateflow 对象按 treeNode / linkNode 的第一个 id（父对象）组成层次，
+// 与 slx 的 simulink/stateflow/*.xml 相同：<chart><Children><state>...</state></Children></chart>
+// chart 的 name 在 mdl 中是完整路径，拆成 name（最后一段）和 path
+func stateflow(sf *node) *xmlNode {
+	root := &xmlNode{XMLName: xml.Name{Local: "Stateflow"}}
+	byID := make(map[string]*xmlNode)
+	parents := make(map[*xmlNode]string)
+	var order []*xmlNode
+
+	for _, obj := range sf.Children {
+		x := &xmlNode{XMLName: xml.Name{Local: obj.Kind}}
+		for _, p := range obj.Params {
+			switch p.Key {
+			case "id":
+				byID[p.Value] = x
+			case "ssIdNumber":
+				x.Attrs = append(x.Attrs, xml.Attr{Name: xml.Name{Local: "SSID"}, Value: p.Value})
+			case "treeNode", "linkNode":
+				if f := strings.Fields(strings.Trim(p.Value, "[]")); len(f) > 0 {
+					parents[x] = f[0]
+				}
+			case "name":
+				if obj.Kind == "chart" {
+					x.Ps = append(x.Ps, xmlP{Name: "path", Value: p.Value})
+					x.Ps = append(x.Ps, xmlP{Name: "name", Value: path.Base(p.Value)})
+					continue
+				}
+				x.Ps = append(x.Ps, xmlP{Name: p.Key, Value: p.Value})
+			default:
+				x.Ps = append(x.Ps, xmlP{Name: p.Key, Value: p.Value})
+			}
+		}
+		// 对象内的子段落（如 data 的 props）不需要，scope / type 等都在对象本身的参数中
+		order = append(order, x)
+	}
+
+	for _, x := range order {
+		parent := byID[parents[x]]
+		if x.XMLName.Local == "chart" || x.XMLName.Local == "machine" || parent == nil || parent == x {
+			root.Children = append(root.Children, x)
+			continue
+		}
+		var children *xmlNode
+		for _, c := range parent.Children {
+			if c.XMLName.Local == "Children" {
+				children = c
+			}
+		}
+		if children == nil {
+			children = &xmlNode{XMLName: xml.Name{Local: "Children"}}
+			parent.Children = append(parent.Children, children)
+		}
+		children.Children = append(children.Children, x)
+	}
+	return root
+}
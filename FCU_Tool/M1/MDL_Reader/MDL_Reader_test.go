@@ -0,0 +1,195 @@
+package MDL_Reader_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"FCU_Tools/M1/MDL_Reader"
+	"FCU_Tools/M1/SLX_Reader"
+	"FCU_Tools/M1/Stateflow_Analysis"
+	"FCU_Tools/M1/System_Tree"
+)
+
+var lim = SLX_Reader.Limits{MaxEntrySize: 1 << 20, MaxTotalSize: 1 << 20}
+
+// 按 System_Tree 读入后的 Block / Line，便于比较
+type block struct {
+	Type, Name, SID string
+}
+
+type line struct {
+	Src, Name, Dst string
+	Branches       []string // 各 Branch 的 Dst
+}
+
+func blocksOf(sys *System_Tree.System) []block {
+	var out []block
+	for _, b := range sys.Blocks {
+		out = append(out, block{b.BlockType, b.Name, b.SID})
+	}
+	return out
+}
+
+func linesOf(sys *System_Tree.System) []line {
+	var out []line
+	for _, l := range sys.Lines {
+		var ln line
+		for _, p := range l.Ps {
+			switch p.Name {
+			case "Src":
+				ln.Src = p.Value
+			case "Name":
+				ln.Name = p.Value
+			case "Dst":
+				ln.Dst = p.Value
+			}
+		}
+		for _, br := range l.Branches {
+			for _, p := range br.Ps {
+				if p.Name == "Dst" {
+					ln.Branches = append(ln.Branches, p.Value)
+				}
+			}
+		}
+		out = append(out, ln)
+	}
+	return out
+}
+
+func TestOpenFixture(t *testing.T) {
+	m, err := MDL_Reader.Open(filepath.Join("testdata", "Fixture.mdl"), lim)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if m.Name != "Fixture" {
+		t.Errorf("Name = %q，期望 Fixture", m.Name)
+	}
+	if !System_Tree.HasRoot(m) {
+		t.Fatal("没有生成 system_root.xml")
+	}
+	tree, err := System_Tree.Load(m)
+	if err != nil {
+		t.Fatalf("System_Tree.Load: %v", err)
+	}
+
+	// 没有 SID 的 Block 从最大 SID（6）往后、按出现顺序编号（先进入 Sub 的 System）
+	wantRoot := []block{
+		{"Inport", "In1", "1"},
+		{"SubSystem", "Sub", "2"},
+		{"Outport", "Out1", "4"},
+		{"Terminator", "T", "9"},
+		{"SubSystem", "Chart", "6"},
+	}
+	if got := blocksOf(tree.Root); !reflect.DeepEqual(got, wantRoot) {
+		t.Errorf("system_root Block:\n got %v\nwant %v", got, wantRoot)
+	}
+
+	// 嵌套的 Branch 展平，非数字端口写成 SID#trigger
+	wantRootLines := []line{
+		{Src: "1#out:1", Name: "sig", Branches: []string{"2#in:1", "2#trigger", "9#in:1"}},
+		{Src: "2#out:1", Dst: "4#in:1"},
+	}
+	if got := linesOf(tree.Root); !reflect.DeepEqual(got, wantRootLines) {
+		t.Errorf("system_root Line:\n got %+v\nwant %+v", got, wantRootLines)
+	}
+
+	sub := tree.Root.Blocks[1]
+	if pc := sub.PortCounts; pc == nil || pc.In != "1" || pc.Out != "1" || pc.Trigger != "1" {
+		t.Errorf("Sub PortCounts = %+v，期望 in=1 out=1 trigger=1", pc)
+	}
+	// 字符串续行拼接，转义还原
+	if got, want := sub.Prop("Description"), "first line\nsecond \"quoted\" line"; got != want {
+		t.Errorf("Description = %q，期望 %q", got, want)
+	}
+	if got := tree.Root.Blocks[4].Prop("SFBlockType"); got != "Chart" {
+		t.Errorf("Chart SFBlockType = %q", got)
+	}
+
+	// 带 System 的 Block 写成 system_<SID>.xml
+	if sub.Child == nil || sub.Child.File != "system_2.xml" {
+		t.Fatalf("Sub 没有对应的 system_2.xml：%+v", sub.Child)
+	}
+	wantSub := []block{
+		{"Inport", "x", "3"},
+		{"TriggerPort", "Trigger", "7"},
+		{"Gain", "G", "8"},
+		{"Outport", "y", "5"},
+	}
+	if got := blocksOf(sub.Child); !reflect.DeepEqual(got, wantSub) {
+		t.Errorf("system_2 Block:\n got %v\nwant %v", got, wantSub)
+	}
+	wantSubLines := []line{
+		{Src: "3#out:1", Dst: "8#in:1"},
+		{Src: "8#out:1", Dst: "5#in:1"},
+	}
+	if got := linesOf(sub.Child); !reflect.DeepEqual(got, wantSubLines) {
+		t.Errorf("system_2 Line:\n got %+v\nwant %+v", got, wantSubLines)
+	}
+	if got := sub.Child.Blocks[1].Prop("TriggerType"); got != "rising" {
+		t.Errorf("TriggerType = %q", got)
+	}
+
+	if _, err := m.FS.Open(SLX_Reader.GraphicalInterface); err != nil {
+		t.Errorf("没有生成 graphicalInterface.xml：%v", err)
+	}
+}
+
+// Stateflow 对象按 treeNode / linkNode 重新组成层次
+func TestOpenFixtureStateflow(t *testing.T) {
+	m, err := MDL_Reader.Open(filepath.Join("testdata", "Fixture.mdl"), lim)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	charts, err := Stateflow_Analysis.GetCharts(m.FS)
+	if err != nil {
+		t.Fatalf("GetCharts: %v", err)
+	}
+	if len(charts) != 1 {
+		t.Fatalf("chart 个数 = %d，期望 1", len(charts))
+	}
+	c := charts[0]
+	if c.Name != "Chart" || c.Path != "Fixture/Chart" {
+		t.Errorf("chart Name / Path = %q / %q", c.Name, c.Path)
+	}
+	if c.States != 2 || c.Transitions != 1 || c.Depth != 2 {
+		t.Errorf("States / Transitions / Depth = %d / %d / %d，期望 2 / 1 / 2", c.States, c.Transitions, c.Depth)
+	}
+	want := []Stateflow_Analysis.Item{{Name: "u", SSID: "5"}}
+	if !reflect.DeepEqual(c.Inputs, want) {
+		t.Errorf("Inputs = %+v，期望 %+v", c.Inputs, want)
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"段落没有结束", "Model {\n  System {\n  }\n"},
+		{"多余的括号", "Model {\n}\n}\n"},
+		{"字符串没有结束", "Model {\n  Name \"abc\n}\n"},
+		{"续行前没有参数", "Model {\n  \"abc\"\n}\n"},
+		{"没有 Model 段落", "Foo {\n}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := filepath.Join(t.TempDir(), "Bad.mdl")
+			if err := os.WriteFile(p, []byte(tt.text), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := MDL_Reader.Open(p, lim); err == nil {
+				t.Error("期望出错")
+			}
+		})
+	}
+
+	// 超过大小上限时与 slx 一样返回 ArchiveError
+	_, err := MDL_Reader.Open(filepath.Join("testdata", "Fixture.mdl"), SLX_Reader.Limits{MaxEntrySize: 16, MaxTotalSize: 16})
+	var ae *SLX_Reader.ArchiveError
+	if !errors.As(err, &ae) || !errors.Is(err, SLX_Reader.ErrTotalTooLarge) {
+		t.Errorf("期望 ErrTotalTooLarge，得到 %v", err)
+	}
+}
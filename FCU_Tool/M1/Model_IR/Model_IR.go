@@ -0,0 +1,225 @@
+package Model_IR
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// 端口类型
+const (
+	PortSR = "S-R"
+	PortCS = "C-S"
+)
+
+// Model 是一个模型的分析结果（中间表示）：模型 → L1 Block（子系统）→ 下一层 Block → ...，每个 Block 带自己的端口
+// 由 Analysis_Process 生成，M1 计算直接使用，不再从 txt 反向解析
+type Model struct {
+	Name   string   `json:"name"`
+	Blocks []*Block `json:"blocks"` // 第 1 层
+	// Models 为组成本模型的所有模型（本模型 + 展开的被引用模型，按首次出现顺序）
+	Models []string `json:"models,omitempty"`
+	// Interface 为模型根层接口（来自 graphicalInterface.xml），没有该文件时为 nil
+	Interface *Interface `json:"interface,omitempty"`
+}
+
+// Interface 是模型根层接口
+//   - Inports / Outports : 根层 S-R 端口
+//   - BusObjects         : 根层端口用到的总线对象
+//   - FunctionCalls      : function-call 输入（Inports 中 OutputFunctionCall=on 的端口名）
+//   - Provides / Requires : C-S 函数（ProvideFunction / RequireFunction）
+//   - DataStores         : 引用的全局 Data Store
+//   - Discrepancies      : 与 system_root.xml 对照时不一致的地方
+//
+// 只用于报告，不参与 M1 计算：根层 S-R 端口即 system_root.xml 中的 Inport / Outport，已作为 L1 Block 的真实端口计入；
+// C-S 函数另外作为 C-S 端口（Port.PortType=C-S）挂在最后一个 L1 Block 上计入
+type Interface struct {
+	Inports       []RootPort `json:"inports,omitempty"`
+	Outports      []RootPort `json:"outports,omitempty"`
+	BusObjects    []string   `json:"bus_objects,omitempty"`
+	FunctionCalls []string   `json:"function_calls,omitempty"`
+	Provides      []string   `json:"provided_functions,omitempty"`
+	Requires      []string   `json:"required_functions,omitempty"`
+	DataStores    []string   `json:"data_stores,omitempty"`
+	Discrepancies []string   `json:"discrepancies,omitempty"`
+}
+
+// RootPort 是根层的一个 Inport / Outport（Port 为端口序号）
+type RootPort struct {
+	Name         string `json:"name"`
+	Port         int    `json:"port"`
+	BusObject    string `json:"bus_object,omitempty"`
+	FunctionCall bool   `json:"function_call,omitempty"`
+	SampleTime   string `json:"sample_time,omitempty"`
+}
+
+// Block 是某一层被选中的 Block（默认 L1/L2 为 SubSystem 或 ModelReference，L3 起为非 Inport/Outport 的 Block）
+type Block struct {
+	Level     int      `json:"level"`
+	Name      string   `json:"name"`
+	SID       string   `json:"sid"`
+	BlockType string   `json:"block_type"`
+	Ports     []Port   `json:"ports,omitempty"`
+	Children  []*Block `json:"children,omitempty"`
+	// ModelReference Block 才有：引用的模型和解析结果，Children 为被引用模型 system_root.xml 中的 Block
+	Reference *Reference `json:"reference,omitempty"`
+	// 库链接 Block（BlockType=Reference）才有：来源和解析结果，Children 为库中来源 Block 的内容
+	Library *LibraryLink `json:"library,omitempty"`
+	// InLibrary 为 true 表示该 Block 来自库（是某个库链接展开后的内容）
+	InLibrary bool `json:"in_library,omitempty"`
+	// Stateflow chart（SubSystem）才有：chart 的结构统计；下一层为一个 BlockStateflow 节点，端口为 chart 的输入输出
+	Chart *Chart `json:"chart,omitempty"`
+	// Variant Subsystem 才有：各 choice 和计入 M1 的 choice，Children 中只保留计入的 choice
+	Variant *Variant `json:"variant,omitempty"`
+}
+
+// Variant 记录一个 Variant Subsystem 按 metrics.m1.variants.mode 选择的结果
+type Variant struct {
+	Mode    string   `json:"mode"`
+	Choices []string `json:"choices"`
+	Chosen  []string `json:"chosen"`
+}
+
+// BlockStateflow 是代表 chart 逻辑的节点类型（取代 chart 内部自动生成的 S-Function 等 Block）
+const BlockStateflow = "Stateflow"
+
+// Chart 是一个 Stateflow chart 的结构统计（来自 simulink/stateflow/*.xml）
+type Chart struct {
+	States      int      `json:"states"`
+	Transitions int      `json:"transitions"`
+	Depth       int      `json:"depth"` // 状态嵌套深度
+	Inputs      []string `json:"inputs,omitempty"`
+	Outputs     []string `json:"outputs,omitempty"`
+	Locals      []string `json:"locals,omitempty"`
+	Events      []string `json:"events,omitempty"`
+	Functions   []string `json:"functions,omitempty"` // 图形函数
+}
+
+// LibraryLink 记录一个库链接 Block
+//   - SourceBlock : 来源，如 "MyLib/Filters/LowPass"
+//   - Status      : RefResolved / RefMissing（库或来源 Block 找不到）
+type LibraryLink struct {
+	SourceBlock string `json:"source_block"`
+	Status      string `json:"status"`
+}
+
+// ModelReference / 库链接的解析结果
+const (
+	RefResolved = "resolved" // 已展开被引用模型
+	RefMissing  = "missing"  // 输入目录中找不到被引用模型（或读取失败）
+	RefCycle    = "cycle"    // 被引用模型已在引用链上（A → B → A），不再展开
+	RefSkipped  = "skipped"  // 未展开（metrics.m1.follow_model_refs 关闭或已到最大深度）
+)
+
+// Reference 记录一个 ModelReference Block 的引用
+//   - Model  : 被引用的模型名
+//   - Chain  : 从被分析模型开始的引用链，最后一个为 Model，如 ["A", "B", "C"]
+//   - Status : RefXxx 之一
+type Reference struct {
+	Model  string   `json:"model"`
+	Chain  []string `json:"chain"`
+	Status string   `json:"status"`
+}
+
+// Port 是 Block 的一个端口
+//   - 真实端口：同一 system 内与该 Block 相连的 Inport / Outport
+//   - 虚拟端口：Block-Block 直接连线生成，Name 为 "A#out:1->B#in:3"，SID 为 "srcSID->dstSID"；
+//     隐式连接（Goto/From 等）生成的 Name 为 "A->B[kind:名字]"
+//   - C-S 端口：来自 graphicalInterface.xml，只挂在 L1，SID 固定为 "unknow"
+type Port struct {
+	Name      string `json:"name"`
+	SID       string `json:"sid"`
+	BlockType string `json:"block_type"` // Inport / Outport
+	PortType  string `json:"port_type"`  // S-R / C-S
+	Virtual   bool   `json:"virtual,omitempty"`
+	Kind      string `json:"kind,omitempty"`    // 虚拟端口的隐式连接类型（goto / datastore / bus_element），<Line> 连接为空
+	Control   string `json:"control,omitempty"` // 连到 Block 的控制端口时的类型（ControlXxx），普通输入输出为空
+}
+
+// 控制端口类型（Port.Control），M1 中另外计数
+const (
+	ControlTrigger      = "trigger"
+	ControlEnable       = "enable"
+	ControlFunctionCall = "function-call"
+	ControlIfAction     = "ifaction"
+)
+
+// Walk 按“先输出同一层所有 Block，再逐个进入下一层”的顺序访问所有 Block（与原 txt 的输出顺序一致）
+// parent 为上一层 Block，L1 为 nil
+func (m *Model) Walk(fn func(b, parent *Block)) {
+	walk(m.Blocks, nil, fn)
+}
+
+func walk(blocks []*Block, parent *Block, fn func(b, parent *Block)) {
+	for _, b := range blocks {
+		fn(b, parent)
+	}
+	for _, b := range blocks {
+		walk(b.Children, b, fn)
+	}
+}
+
+// WriteJSON 把中间表示写成 JSON 文件
+func (m *Model) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化中间表示失败 [%s]: %v", m.Name, err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("写入中间表示失败 [%s]: %v", path, err)
+	}
+	return nil
+}
+
+// WriteTxt 把中间表示按原来的 txt 格式写出（仅供人工查看，程序不再读取）：
+// [Lx] Name: <BlockName>	BlockType=<BlockType>	SID=<SID> [FatherNode=xxx]
+//
+//	[Lx Port] Name: <真实Port名>	BlockType=<In/Outport>	SID=<SID> [PortType=S-R]
+//	[Lx virtual Port] Name: <BlockA#out:1->BlockB#in:3>	BlockType=<In/Outport>	SID=<69->147> ...
+func (m *Model) WriteTxt(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("无法写入 txt 文件 [%s]: %w", path, err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	m.Walk(func(b, parent *Block) {
+		writeBlockTxt(w, b, parent)
+	})
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("无法写入 txt 文件 [%s]: %w", path, err)
+	}
+	return nil
+}
+
+func writeBlockTxt(w io.Writer, b, parent *Block) {
+	// 先输出 Block 自身信息，带上 FatherNode（从第二层开始）
+	if parent != nil && b.Level >= 2 {
+		fmt.Fprintf(w, "[L%d] Name: %-10s\tBlockType=%-10s\tSID=%-10s\tFatherNode=%-10s\n",
+			b.Level, b.Name, b.BlockType, b.SID, parent.Name)
+	} else {
+		fmt.Fprintf(w, "[L%d] Name: %s\tBlockType=%s\tSID=%s\n",
+			b.Level, b.Name, b.BlockType, b.SID)
+	}
+
+	// 再输出这个 Block 的所有 Port / 伪 Port 信息
+	for _, p := range b.Ports {
+		// 根据是否虚拟端口，选择不同标签
+		label := "Port"
+		if p.Virtual {
+			label = "virtual Port"
+		}
+
+		// L1 才输出 PortType；L2 及以后不输出 PortType
+		if b.Level == 1 {
+			fmt.Fprintf(w, "\t[L%d %s] Name: %-40s\tBlockType=%-10s\tSID=%-10s\tPortType=%-10s\n",
+				b.Level, label, p.Name, p.BlockType, p.SID, p.PortType)
+		} else {
+			fmt.Fprintf(w, "\t[L%d %s] Name:%-40s\tBlockType=%-10s\tSID=%-10s\n",
+				b.Level, label, p.Name, p.BlockType, p.SID)
+		}
+	}
+}
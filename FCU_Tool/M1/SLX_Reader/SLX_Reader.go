@@ -0,0 +1,280 @@
+package SLX_Reader
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// slx 包内 M1 分析用到的文件（zip 内路径，统一用 "/"）
+const (
+	SystemsDir         = "simulink/systems"
+	GraphicalInterface = "simulink/graphicalInterface.xml"
+	BlockDiagram       = "simulink/blockdiagram.xml"
+	StateflowDir       = "simulink/stateflow"
+)
+
+// Limits 是解压大小上限（字节），来自 metrics.m1.max_entry_mb / max_total_mb
+type Limits struct {
+	MaxEntrySize int64 // 单个条目解压后的最大大小
+	MaxTotalSize int64 // 一个 slx 内所有读取条目解压后的总大小
+}
+
+// 拒绝 slx 的原因，可用 errors.Is 判断
+var (
+	ErrUnsafePath    = errors.New("条目路径不安全（绝对路径或跳出解压目录）")
+	ErrSymlink       = errors.New("条目是符号链接")
+	ErrEntryTooLarge = errors.New("条目解压后超过大小上限")
+	ErrTotalTooLarge = errors.New("解压总大小超过上限")
+)
+
+// ArchiveError 是读取或解压某个 slx 失败的原因
+//   - Archive : slx 文件路径
+//   - Entry   : 出问题的 zip 内条目名（原样）
+//   - Err     : 上面的 ErrXxx 之一，或底层读取错误
+//   - Detail  : 补充说明（如大小上限）
+type ArchiveError struct {
+	Archive string
+	Entry   string
+	Err     error
+	Detail  string
+}
+
+func (e *ArchiveError) Error() string {
+	msg := fmt.Sprintf("slx 读取被拒绝 [%s] 条目 %q: %v", e.Archive, e.Entry, e.Err)
+	if e.Detail != "" {
+		msg += "（" + e.Detail + "）"
+	}
+	return msg
+}
+
+func (e *ArchiveError) Unwrap() error { return e.Err }
+
+// Model 是一个已读入内存的 slx 模型
+//   - Name : 模型名（slx 文件名去掉扩展名）
+//   - Path : slx 文件路径，只用于提示信息
+//   - FS   : 只包含 simulink/systems/*.xml、simulink/stateflow/*.xml、graphicalInterface.xml、blockdiagram.xml 的内存文件系统
+//     （支持 fs.ReadFile / fs.Stat / fs.Glob）
+type Model struct {
+	Name string
+	Path string
+	FS   fs.FS
+}
+
+// Open 直接读取 slx(zip)，把 M1 需要的 xml 读进内存，不再复制、解压到 M1/build
+// 所有条目都先经过 checkEntry 检查，读入的内容受 lim 限制
+func Open(slxPath string, lim Limits) (*Model, error) {
+	r, err := zip.OpenReader(slxPath)
+	if err != nil {
+		return nil, fmt.Errorf("打开 slx 失败 [%s]: %w", slxPath, err)
+	}
+	defer r.Close()
+
+	files := make(memFS)
+	var total int64
+	for _, f := range r.File {
+		name, err := checkEntry(slxPath, f)
+		if err != nil {
+			return nil, err
+		}
+		if f.FileInfo().IsDir() || !wanted(name) {
+			continue
+		}
+		data, err := readEntry(slxPath, name, f, lim, &total)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = &memFile{name: name, data: data, modTime: f.Modified}
+	}
+
+	base := filepath.Base(slxPath)
+	return &Model{
+		Name: strings.TrimSuffix(base, filepath.Ext(base)),
+		Path: slxPath,
+		FS:   files,
+	}, nil
+}
+
+// NewModel 用已经读出的文件（包内路径 → 内容）构造 Model，供其他格式（如 .mdl）转换成 slx 的文件结构后使用
+func NewModel(name, modelPath string, files map[string][]byte, modTime time.Time) *Model {
+	fsys := make(memFS)
+	for n, data := range files {
+		fsys[n] = &memFile{name: n, data: data, modTime: modTime}
+	}
+	return &Model{Name: name, Path: modelPath, FS: fsys}
+}
+
+// Extract 把整个 slx 解压到 destDir（metrics.m1.extract 调试用）
+// 条目路径必须留在 destDir 内，不允许符号链接，解压大小受 lim 限制；出错时 destDir 可能只解压了一部分
+func Extract(slxPath, destDir string, lim Limits) error {
+	r, err := zip.OpenReader(slxPath)
+	if err != nil {
+		return fmt.Errorf("打开 slx 失败 [%s]: %w", slxPath, err)
+	}
+	defer r.Close()
+
+	// 先检查全部条目，避免写了一半才发现压缩包有问题
+	names := make([]string, len(r.File))
+	for i, f := range r.File {
+		if names[i], err = checkEntry(slxPath, f); err != nil {
+			return err
+		}
+	}
+
+	var total int64
+	for i, f := range r.File {
+		targetPath := filepath.Join(destDir, filepath.FromSlash(names[i]))
+
+		// 目录
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(targetPath, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		data, err := readEntry(slxPath, names[i], f, lim, &total)
+		if err != nil {
+			return err
+		}
+
+		// 确保上级目录存在
+		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(targetPath, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Location 返回 slx 内某个文件的提示用路径，例如 models/A/A.slx:simulink/systems/system_root.xml
+func (m *Model) Location(name string) string {
+	return m.Path + ":" + name
+}
+
+func wanted(name string) bool {
+	if name == GraphicalInterface || name == BlockDiagram {
+		return true
+	}
+	for _, dir := range []string{SystemsDir, StateflowDir} {
+		if ok, _ := path.Match(dir+"/*.xml", name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkEntry 检查 zip 条目，返回规整后的包内路径（统一用 "/"）
+//   - 路径不能是绝对路径、带盘符或包含 ".."（zip-slip）
+//   - 不能是符号链接
+func checkEntry(slxPath string, f *zip.File) (string, error) {
+	name := strings.TrimSuffix(strings.ReplaceAll(f.Name, "\\", "/"), "/")
+	if !fs.ValidPath(name) || name == "." || !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", &ArchiveError{Archive: slxPath, Entry: f.Name, Err: ErrUnsafePath}
+	}
+	if f.Mode()&fs.ModeSymlink != 0 {
+		return "", &ArchiveError{Archive: slxPath, Entry: f.Name, Err: ErrSymlink}
+	}
+	return name, nil
+}
+
+// readEntry 读出一个条目，按实际解压出的字节数检查单个条目和累计（*total）大小，
+// 不相信 zip 头里声明的大小
+func readEntry(slxPath, name string, f *zip.File, lim Limits, total *int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, &ArchiveError{Archive: slxPath, Entry: name, Err: err}
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, lim.MaxEntrySize+1))
+	if err != nil {
+		return nil, &ArchiveError{Archive: slxPath, Entry: name, Err: err}
+	}
+	if int64(len(data)) > lim.MaxEntrySize {
+		return nil, &ArchiveError{Archive: slxPath, Entry: name, Err: ErrEntryTooLarge,
+			Detail: fmt.Sprintf("上限 %d 字节", lim.MaxEntrySize)}
+	}
+	*total += int64(len(data))
+	if *total > lim.MaxTotalSize {
+		return nil, &ArchiveError{Archive: slxPath, Entry: name, Err: ErrTotalTooLarge,
+			Detail: fmt.Sprintf("上限 %d 字节", lim.MaxTotalSize)}
+	}
+	return data, nil
+}
+
+// ======================== 内存文件系统 ========================
+
+// memFS 按 zip 内路径保存文件内容，只支持打开文件和 Glob（不列目录）
+type memFS map[string]*memFile
+
+type memFile struct {
+	name    string
+	data    []byte
+	modTime time.Time
+}
+
+func (m memFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	f, ok := m[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &openFile{memFile: f, Reader: bytes.NewReader(f.data)}, nil
+}
+
+// ReadFile 直接返回内容副本，避免 fs.ReadFile 再走一遍 Open/Read
+func (m memFS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+	f, ok := m[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]byte(nil), f.data...), nil
+}
+
+// Glob 让 fs.Glob 不需要列目录，按名字排序返回
+func (m memFS) Glob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	var names []string
+	for name := range m {
+		if ok, _ := path.Match(pattern, name); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+type openFile struct {
+	*memFile
+	*bytes.Reader
+}
+
+func (f *openFile) Stat() (fs.FileInfo, error) { return f.memFile, nil }
+func (f *openFile) Close() error               { return nil }
+
+// memFile 同时实现 fs.FileInfo
+func (f *memFile) Name() string       { return path.Base(f.name) }
+func (f *memFile) Size() int64        { return int64(len(f.data)) }
+func (f *memFile) Mode() fs.FileMode  { return 0444 }
+func (f *memFile) ModTime() time.Time { return f.modTime }
+func (f *memFile) IsDir() bool        { return false }
+func (f *memFile) Sys() any           { return nil }
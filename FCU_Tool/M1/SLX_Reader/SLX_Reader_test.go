@@ -0,0 +1,160 @@
+package SLX_Reader
+
+import (
+	"archive/zip"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 测试用的 zip 条目
+type testEntry struct {
+	name string
+	data string
+	mode fs.FileMode // 0 表示普通文件
+}
+
+// writeSlx 在临时目录中写出由 entries 组成的 slx，返回其路径
+func writeSlx(t *testing.T, entries []testEntry) string {
+	t.Helper()
+	slxPath := filepath.Join(t.TempDir(), "M.slx")
+	f, err := os.Create(slxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for _, e := range entries {
+		h := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		if e.mode != 0 {
+			h.SetMode(e.mode)
+		}
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return slxPath
+}
+
+func TestOpenRejectsUnsafeArchives(t *testing.T) {
+	root := SystemsDir + "/system_root.xml"
+	lim := Limits{MaxEntrySize: 64, MaxTotalSize: 100}
+
+	tests := []struct {
+		name    string
+		entries []testEntry
+		want    error
+		entry   string // ArchiveError.Entry
+	}{
+		{
+			name:    "上级目录",
+			entries: []testEntry{{name: "../evil.xml", data: "x"}},
+			want:    ErrUnsafePath,
+			entry:   "../evil.xml",
+		},
+		{
+			name:    "包内上级目录",
+			entries: []testEntry{{name: "simulink/../../evil.xml", data: "x"}},
+			want:    ErrUnsafePath,
+			entry:   "simulink/../../evil.xml",
+		},
+		{
+			name:    "绝对路径",
+			entries: []testEntry{{name: "/etc/evil.xml", data: "x"}},
+			want:    ErrUnsafePath,
+			entry:   "/etc/evil.xml",
+		},
+		{
+			name:    "反斜杠上级目录",
+			entries: []testEntry{{name: `..\evil.xml`, data: "x"}},
+			want:    ErrUnsafePath,
+			entry:   `..\evil.xml`,
+		},
+		{
+			name:    "符号链接",
+			entries: []testEntry{{name: root, data: "/etc/passwd", mode: fs.ModeSymlink | 0777}},
+			want:    ErrSymlink,
+			entry:   root,
+		},
+		{
+			name:    "单个条目超过上限",
+			entries: []testEntry{{name: root, data: strings.Repeat("x", 65)}},
+			want:    ErrEntryTooLarge,
+			entry:   root,
+		},
+		{
+			name: "总大小超过上限",
+			entries: []testEntry{
+				{name: root, data: strings.Repeat("x", 60)},
+				{name: SystemsDir + "/system_1.xml", data: strings.Repeat("x", 41)},
+			},
+			want:  ErrTotalTooLarge,
+			entry: SystemsDir + "/system_1.xml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slxPath := writeSlx(t, tt.entries)
+
+			for op, run := range map[string]func() error{
+				"Open": func() error {
+					m, err := Open(slxPath, lim)
+					if err == nil && m == nil {
+						t.Error("Open 返回 nil, nil")
+					}
+					return err
+				},
+				"Extract": func() error { return Extract(slxPath, t.TempDir(), lim) },
+			} {
+				err := run()
+				var ae *ArchiveError
+				if !errors.As(err, &ae) {
+					t.Fatalf("%s: 期望 *ArchiveError，得到 %v", op, err)
+				}
+				if !errors.Is(err, tt.want) {
+					t.Errorf("%s: 期望 %v，得到 %v", op, tt.want, ae.Err)
+				}
+				if ae.Archive != slxPath || ae.Entry != tt.entry {
+					t.Errorf("%s: Archive / Entry = %q / %q，期望 %q / %q", op, ae.Archive, ae.Entry, slxPath, tt.entry)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenWithinLimits(t *testing.T) {
+	root := SystemsDir + "/system_root.xml"
+	slxPath := writeSlx(t, []testEntry{
+		{name: root, data: strings.Repeat("x", 64)},
+		{name: SystemsDir + "/system_1.xml", data: strings.Repeat("x", 36)},
+		// 不读取的条目不计入大小
+		{name: "metadata/thumbnail.png", data: strings.Repeat("x", 1000)},
+	})
+
+	m, err := Open(slxPath, Limits{MaxEntrySize: 64, MaxTotalSize: 100})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if m.Name != "M" {
+		t.Errorf("Name = %q，期望 M", m.Name)
+	}
+	data, err := fs.ReadFile(m.FS, root)
+	if err != nil || len(data) != 64 {
+		t.Errorf("读取 %s: %d 字节, %v", root, len(data), err)
+	}
+	if _, err := fs.Stat(m.FS, "metadata/thumbnail.png"); err == nil {
+		t.Error("不需要的条目不应读入")
+	}
+}
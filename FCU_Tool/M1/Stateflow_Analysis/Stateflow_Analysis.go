@@ -0,0 +1,216 @@
+package Stateflow_Analysis
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"FCU_Tools/M1/SLX_Reader"
+)
+
+// 对外暴露的 Stateflow chart 信息
+//   - Name / Path  : chart 名和 Simulink 路径（如 Model/Sub/Chart，旧版本才有 path，可能为空）
+//   - States       : 状态数（不含图形函数和 box）
+//   - Transitions  : 迁移数（包括图形函数内部的迁移）
+//   - Depth        : 状态嵌套深度（只有顶层状态为 1，没有状态为 0）
+//   - Inputs / Outputs / Locals : scope 为 Input / Output / Local 的 data
+//   - Events       : event（Inputs / Outputs 中的 event 同时也算作输入输出）
+//   - Functions    : 图形函数名
+type Chart struct {
+	Name        string
+	Path        string
+	States      int
+	Transitions int
+	Depth       int
+	Inputs      []Item
+	Outputs     []Item
+	Locals      []Item
+	Events      []Item
+	Functions   []string
+}
+
+// Item 是 chart 的一个 data 或 event
+//   - Event : true 表示 event（输入 event 即 chart 的 trigger 端口）
+type Item struct {
+	Name  string
+	SSID  string
+	Event bool
+}
+
+// 内部 XML 结构：stateflow 的 xml 层次较深，元素名各版本略有不同，这里统一按通用节点读入
+type xmlP struct {
+	Name  string `xml:"Name,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Ps      []xmlP     `xml:"P"`
+	Nodes   []xmlNode  `xml:",any"`
+}
+
+func (n *xmlNode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return strings.TrimSpace(a.Value)
+		}
+	}
+	return ""
+}
+
+func (n *xmlNode) prop(name string) string {
+	for _, p := range n.Ps {
+		if p.Name == name {
+			return strings.TrimSpace(p.Value)
+		}
+	}
+	return ""
+}
+
+// 元素名：有的版本用 name 属性，有的用 <P Name="name">
+func (n *xmlNode) name() string {
+	if v := n.attr("name"); v != "" {
+		return v
+	}
+	return n.prop("name")
+}
+
+// children 返回 <Children> 下的元素（没有 <Children> 包裹时直接返回子元素）
+func (n *xmlNode) children() []xmlNode {
+	var out []xmlNode
+	for _, c := range n.Nodes {
+		if c.XMLName.Local == "Children" {
+			out = append(out, c.Nodes...)
+		} else {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// 从 slx 内的 simulink/stateflow/*.xml 中解析所有 chart（按文件名、文件内顺序）
+// 没有 stateflow 目录的模型返回空列表
+func GetCharts(fsys fs.FS) ([]*Chart, error) {
+	var result []*Chart
+
+	if fsys == nil {
+		return result, nil
+	}
+
+	names, err := fs.Glob(fsys, path.Join(SLX_Reader.StateflowDir, "*.xml"))
+	if err != nil {
+		return result, fmt.Errorf("查找 stateflow 文件失败: %w", err)
+	}
+
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return result, fmt.Errorf("读取 stateflow 文件失败 [%s]: %w", name, err)
+		}
+
+		var root xmlNode
+		if err := xml.Unmarshal(data, &root); err != nil {
+			return result, fmt.Errorf("解析 stateflow 文件失败 [%s]: %w", name, err)
+		}
+		findCharts(&root, &result)
+	}
+	return result, nil
+}
+
+// findCharts 在整个 xml 树中找 <chart> 元素
+func findCharts(n *xmlNode, out *[]*Chart) {
+	if n.XMLName.Local == "chart" {
+		c := &Chart{Name: n.name(), Path: n.prop("path")}
+		if c.Name == "" && c.Path != "" {
+			c.Name = path.Base(c.Path)
+		}
+		c.Depth = walkChart(n, c, 0)
+		*out = append(*out, c)
+		return
+	}
+	for i := range n.Nodes {
+		findCharts(&n.Nodes[i], out)
+	}
+}
+
+// walkChart 统计 n 下面的状态、迁移、data、event、图形函数，返回状态嵌套深度
+// depth 为 n 本身的状态嵌套层数（chart 为 0）
+func walkChart(n *xmlNode, c *Chart, depth int) int {
+	maxDepth := depth
+	for _, child := range n.children() {
+		switch child.XMLName.Local {
+		case "state":
+			switch child.prop("type") {
+			case "FUNC_STATE":
+				c.Functions = append(c.Functions, functionName(child.prop("labelString")))
+				walkChart(&child, c, depth) // 函数内部的状态不计入嵌套深度，迁移照常计数
+			case "GROUP_STATE":
+				if d := walkChart(&child, c, depth); d > maxDepth { // box 只是分组
+					maxDepth = d
+				}
+			default:
+				c.States++
+				if d := walkChart(&child, c, depth+1); d > maxDepth {
+					maxDepth = d
+				}
+			}
+		case "transition":
+			c.Transitions++
+		case "data":
+			item := Item{Name: child.name(), SSID: child.attr("SSID")}
+			switch child.prop("scope") {
+			case "INPUT_DATA":
+				c.Inputs = append(c.Inputs, item)
+			case "OUTPUT_DATA":
+				c.Outputs = append(c.Outputs, item)
+			case "LOCAL_DATA":
+				c.Locals = append(c.Locals, item)
+			}
+		case "event":
+			item := Item{Name: child.name(), SSID: child.attr("SSID"), Event: true}
+			c.Events = append(c.Events, item)
+			switch child.prop("scope") {
+			case "INPUT_EVENT":
+				c.Inputs = append(c.Inputs, item)
+			case "OUTPUT_EVENT":
+				c.Outputs = append(c.Outputs, item)
+			}
+		}
+	}
+	return maxDepth
+}
+
+// "y = f(x)\nentry: ..." → "f"
+func functionName(label string) string {
+	label, _, _ = strings.Cut(label, "\n")
+	label, _, _ = strings.Cut(label, "(")
+	if _, after, ok := strings.Cut(label, "="); ok {
+		label = after
+	}
+	return strings.TrimSpace(label)
+}
+
+// FindChart 按 Simulink 路径找 chart；chart 没有 path 时按名字找，名字重复时返回 nil
+func FindChart(charts []*Chart, blockPath, name string) *Chart {
+	var byName []*Chart
+	for _, c := range charts {
+		if c.Path != "" && normalize(c.Path) == normalize(blockPath) {
+			return c
+		}
+		if normalize(c.Name) == normalize(name) {
+			byName = append(byName, c)
+		}
+	}
+	if len(byName) == 1 {
+		return byName[0]
+	}
+	return nil
+}
+
+// 把名字里的换行 / 多余空白压成一个空格
+func normalize(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -1,133 +1,62 @@
 package System_Analysis
 
 import (
-	"encoding/xml"
-	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 
+	"FCU_Tools/Config"
+	"FCU_Tools/M1/Connection_Analysis"
+	"FCU_Tools/M1/Model_IR"
 	"FCU_Tools/M1/Port_Analysis"
+	"FCU_Tools/M1/System_Tree"
 )
 
 // 用来保存 SubSystem 的 Name / SID / Level / BlockType
+// Child 为下一层 system（没有对应 system_<SID>.xml 时为 nil）
+// Node 为该 Block 的中间表示（带端口），下一层的 Block 挂到 Node.Children
+// RefModel 为 ModelReference Block 引用的模型名（其他 Block 为空），由 Analysis_Process 到其他 slx 中展开
+// Link 为库链接 Block 的解析结果（其他 Block 为 nil）；解析成功时 Child 为库中来源 Block 的内容
+// SFBlockType 为 Stateflow Block 的类型（Chart 等，其他 Block 为空）
+// Block 为系统树中的 Block（库链接解析成功时为库中的来源 Block），用于读取 Variant 等属性
 type SubSystemInfo struct {
-	Name      string
-	SID       string
-	Level     int
-	BlockType string
-}
-
-// P 标签
-type xmlP struct {
-	Name  string `xml:"Name,attr"`
-	Value string `xml:",chardata"`
-}
-
-// PortCounts 标签
-type xmlPortCounts struct {
-	In      string `xml:"in,attr"`
-	Out     string `xml:"out,attr"`
-	Trigger string `xml:"trigger,attr"`
-}
-
-// Block
-type xmlBlock struct {
-	BlockType  string         `xml:"BlockType,attr"`
-	Name       string         `xml:"Name,attr"`
-	SID        string         `xml:"SID,attr"`
-	PortCounts *xmlPortCounts `xml:"PortCounts"`
-	Properties []xmlP         `xml:"P"`
-}
-
-type xmlSystem struct {
-	Blocks []xmlBlock `xml:"Block"`
+	Name        string
+	SID         string
+	Level       int
+	BlockType   string
+	Child       *System_Tree.System
+	Node        *Model_IR.Block
+	RefModel    string
+	Link        *System_Tree.Link
+	SFBlockType string
+	Block       *System_Tree.Block
 }
 
 // ======================== 对外入口 ================================
-// fatherName：当前 system_xxx.xml 对应的父节点名称（L1 为空串）
-func AnalyzeSubSystemsInFile(dir, file string, level int, fatherName string) ([]SubSystemInfo, error) {
-	switch level {
-	case 1:
-		return analyzeSubSystemsLevel1(dir, file, level, fatherName)
-	case 2:
-		return analyzeSubSystemsLevel2(dir, file, level, fatherName)
-	case 3:
-		return analyzeSubSystemsLevel3(dir, file, level, fatherName)
-	default:
-		// 第 3 层及以后统一按“非 Inport/Outport Block”处理
-		return analyzeSubSystemsLevel3(dir, file, level, fatherName)
-	}
-}
-
-// ======================== 逻辑 1（L1：过滤无效 SubSystem） ================================
-func analyzeSubSystemsLevel1(dir, file string, level int, fatherName string) ([]SubSystemInfo, error) {
-	return analyzeSubSystemsCommon(dir, file, level, true, fatherName)
-}
-
-// ======================== 逻辑 2（L2：不过滤 SubSystem） ================================
-func analyzeSubSystemsLevel2(dir, file string, level int, fatherName string) ([]SubSystemInfo, error) {
-	return analyzeSubSystemsCommon(dir, file, level, false, fatherName)
-}
-
-// ======================== 逻辑 3（L3+：非 Inport/Outport Block） =========================
-func analyzeSubSystemsLevel3(dir, file string, level int, fatherName string) ([]SubSystemInfo, error) {
-	return analyzeNonPortBlocks(dir, file, level, fatherName)
-}
-
-// ======================== 通用 SubSystem 分析（移除递归，由外层控制） ====================
-func analyzeSubSystemsCommon(dir, file string, level int, applyLevel1Filter bool, fatherName string) ([]SubSystemInfo, error) {
-
-	fullPath := filepath.Join(dir, file)
-
-	data, err := os.ReadFile(fullPath)
-	if err != nil {
-		return nil, fmt.Errorf("读取 XML 失败 [%s]: %w", fullPath, err)
-	}
-
-	var sys xmlSystem
-	if err := xml.Unmarshal(data, &sys); err != nil {
-		return nil, fmt.Errorf("解析 XML 失败 [%s]: %w", fullPath, err)
-	}
-
-	// 推出模型名：BuildDir/<Model>/simulink/systems → <Model>
-	modelDir := filepath.Dir(filepath.Dir(dir))
-	modelName := filepath.Base(modelDir)
-
+// model / sys：已解析的系统树及其中当前这一层 system
+// rule：本层的 Block 选择规则（metrics.m1.levels，默认 L1 有端口的 SubSystem/ModelReference / L2 SubSystem/ModelReference / L3+ 非 Inport/Outport）
+// lib：库链接（BlockType=Reference）到这里解析；解析成功的库链接按库中来源 Block 的类型筛选（如链接的 SubSystem 按 SubSystem 处理）
+// conn：计入端口的连接（metrics.m1.connection_kinds / metrics.m1.variants）
+func AnalyzeSubSystems(model *System_Tree.Model, sys *System_Tree.System, level int, rule Config.M1LevelRule, lib *System_Tree.Library, conn Connection_Analysis.Options) []SubSystemInfo {
 	var result []SubSystemInfo
 	var blockSIDs []string
 
 	for _, b := range sys.Blocks {
 
-		if b.BlockType != "SubSystem" {
-			continue
-		}
-
-		// === level=1 时过滤：Ports 为空 / PortCounts 为空的 SubSystem 直接跳过 ===
-		if applyLevel1Filter && level == 1 {
-			invalid := false
-
-			// (1) Ports = []
-			for _, p := range b.Properties {
-				if p.Name == "Ports" {
-					v := strings.TrimSpace(p.Value)
-					if v == "[]" || v == "" {
-						invalid = true
-						break
-					}
-				}
+		blockType, child, block := b.BlockType, b.Child, b
+		var link *System_Tree.Link
+		if src := b.SourceBlock(); src != "" && lib != nil {
+			link = lib.ResolveLink(src)
+			if link.Err == nil {
+				blockType, child, block = link.Block.BlockType, link.Block.Child, link.Block
 			}
+		}
 
-			// (2) PortCounts 标签存在但为空
-			if !invalid && b.PortCounts != nil {
-				if b.PortCounts.In == "" && b.PortCounts.Out == "" && b.PortCounts.Trigger == "" {
-					invalid = true
-				}
-			}
+		if !rule.Selects(blockType) {
+			continue
+		}
 
-			if invalid {
-				continue
-			}
+		// === require_ports：Ports 为空 / PortCounts 为空的 Block 直接跳过 ===
+		if rule.RequirePorts && !hasPorts(b) {
+			continue
 		}
 
 		// 名字做一次规整，去掉换行、多空格
@@ -135,75 +64,55 @@ func analyzeSubSystemsCommon(dir, file string, level int, applyLevel1Filter bool
 		name := strings.Join(strings.Fields(rawName), " ")
 
 		info := SubSystemInfo{
-			Name:      name,
-			SID:       b.SID,
-			Level:     level,
-			BlockType: b.BlockType, // "SubSystem"
+			Name:        name,
+			SID:         b.SID,
+			Level:       level,
+			BlockType:   blockType,
+			Child:       child,
+			RefModel:    b.ReferencedModel(),
+			Link:        link,
+			SFBlockType: b.Prop("SFBlockType"),
+			Block:       block,
 		}
 		result = append(result, info)
 		blockSIDs = append(blockSIDs, b.SID)
 	}
 
-	// 把本层要输出的 BlockSID 列表交给 Port_Analysis，由它按 Block → Port 顺序统一输出
-	if len(blockSIDs) > 0 && modelName != "" {
-		if err := Port_Analysis.AnalyzePortsInFile(dir, file, level, modelName, fatherName, blockSIDs); err != nil {
-			fmt.Printf("⚠️ Port_Analysis 分析失败 [%s]: %v\n", fullPath, err)
-		}
-	}
-
-	return result, nil
+	// 把本层要输出的 BlockSID 列表交给 Port_Analysis，由它按 Block → Port 顺序生成中间表示
+	return withNodes(result, model, sys, level, blockSIDs, conn)
 }
 
-// ======================== 非 Inport / Outport Block 分析（第 3 层及以后） ==================
-// 在指定 system_xxx.xml 中，找到所有 BlockType != "Inport" 且 != "Outport" 的 Block，
-// 记录这些 Block 的 Name / BlockType / SID，并交给 Port_Analysis 做统一输出。
-func analyzeNonPortBlocks(dir, file string, level int, fatherName string) ([]SubSystemInfo, error) {
-	fullPath := filepath.Join(dir, file)
-
-	data, err := os.ReadFile(fullPath)
-	if err != nil {
-		return nil, fmt.Errorf("读取 XML 失败 [%s]: %w", fullPath, err)
-	}
-
-	var sys xmlSystem
-	if err := xml.Unmarshal(data, &sys); err != nil {
-		return nil, fmt.Errorf("解析 XML 失败 [%s]: %w", fullPath, err)
-	}
-
-	// 推出模型名：BuildDir/<Model>/simulink/systems → <Model>
-	modelDir := filepath.Dir(filepath.Dir(dir))
-	modelName := filepath.Base(modelDir)
-
-	var result []SubSystemInfo
-	var blockSIDs []string
-
-	for _, b := range sys.Blocks {
-
-		// 跳过 Inport 和 Outport
-		if b.BlockType == "Inport" || b.BlockType == "Outport" {
-			continue
-		}
-
-		rawName := strings.TrimSpace(b.Name)
-		name := strings.Join(strings.Fields(rawName), " ")
-
-		info := SubSystemInfo{
-			Name:      name,
-			SID:       b.SID,
-			Level:     level,
-			BlockType: b.BlockType,
+// hasPorts 判断 Block 是否有端口：
+// (1) Ports = [] 或为空 → 没有
+// (2) PortCounts 标签存在但为空 → 没有
+func hasPorts(b *System_Tree.Block) bool {
+	for _, p := range b.Properties {
+		if p.Name == "Ports" {
+			v := strings.TrimSpace(p.Value)
+			if v == "[]" || v == "" {
+				return false
+			}
 		}
-
-		result = append(result, info)
-		blockSIDs = append(blockSIDs, b.SID)
 	}
-
-	// 交给 Port_Analysis 做 Block + Port 的统一输出
-	if len(blockSIDs) > 0 && modelName != "" {
-		if err := Port_Analysis.AnalyzePortsInFile(dir, file, level, modelName, fatherName, blockSIDs); err != nil {
-			fmt.Printf("⚠️ Port_Analysis 分析失败 [%s]: %v\n", fullPath, err)
+	if b.PortCounts != nil {
+		if b.PortCounts.In == "" && b.PortCounts.Out == "" && b.PortCounts.Trigger == "" {
+			return false
 		}
 	}
+	return true
+}
 
-	return result, nil
+// withNodes 用 Port_Analysis 生成本层 Block 的中间表示，并按 SID 填到 result[i].Node
+func withNodes(result []SubSystemInfo, model *System_Tree.Model, sys *System_Tree.System, level int, blockSIDs []string, conn Connection_Analysis.Options) []SubSystemInfo {
+	if len(blockSIDs) == 0 {
+		return result
+	}
+	nodes := make(map[string]*Model_IR.Block)
+	for _, n := range Port_Analysis.AnalyzePorts(model, sys, level, blockSIDs, conn) {
+		nodes[n.SID] = n
+	}
+	for i := range result {
+		result[i].Node = nodes[result[i].SID]
+	}
+	return result
 }
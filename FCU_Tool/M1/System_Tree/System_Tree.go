@@ -0,0 +1,375 @@
+package System_Tree
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"FCU_Tools/M1/MDL_Reader"
+	"FCU_Tools/M1/SLX_Reader"
+)
+
+// P 标签
+type P struct {
+	Name  string `xml:"Name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// PortCounts 标签
+type PortCounts struct {
+	In      string `xml:"in,attr"`
+	Out     string `xml:"out,attr"`
+	Trigger string `xml:"trigger,attr"`
+}
+
+// Block 标签；Child 为该 Block 对应的下一层 system_<SID>.xml（没有则为 nil）
+type Block struct {
+	BlockType  string      `xml:"BlockType,attr"`
+	Name       string      `xml:"Name,attr"`
+	SID        string      `xml:"SID,attr"`
+	PortCounts *PortCounts `xml:"PortCounts"`
+	Properties []P         `xml:"P"`
+
+	Child *System `xml:"-"`
+}
+
+// 引用其他 slx 内容的 Block 类型
+const (
+	BlockModelReference = "ModelReference" // 引用其他模型
+	BlockLibraryLink    = "Reference"      // 库链接，内容来自库 slx 中的 SourceBlock
+)
+
+// ReferencedModel 返回 ModelReference Block 引用的模型名（不带路径和扩展名），其他 Block 返回 ""
+// 不同版本的 Simulink 把模型名写在 ModelName / ModelNameDialog / ModelFile 中，取第一个非空的
+func (b *Block) ReferencedModel() string {
+	if b.BlockType != BlockModelReference {
+		return ""
+	}
+	for _, key := range []string{"ModelName", "ModelNameDialog", "ModelFile"} {
+		for _, p := range b.Properties {
+			if p.Name != key {
+				continue
+			}
+			v := strings.TrimSpace(p.Value)
+			if v == "" {
+				continue
+			}
+			v = path.Base(strings.ReplaceAll(v, "\\", "/"))
+			return strings.TrimSuffix(v, path.Ext(v))
+		}
+	}
+	return ""
+}
+
+// Branch 标签
+type Branch struct {
+	Ps []P `xml:"P"`
+}
+
+// Line 标签
+type Line struct {
+	Ps       []P      `xml:"P"`
+	Branches []Branch `xml:"Branch"`
+}
+
+// System 是一个 system_xxx.xml 解析后的内容
+type System struct {
+	File   string   `xml:"-"` // simulink/systems 下的文件名，如 system_root.xml
+	Blocks []*Block `xml:"Block"`
+	Lines  []Line   `xml:"Line"`
+
+	Parent *System `xml:"-"` // 上一层 system（system_root 为 nil）
+}
+
+// Model 是一个模型解析后的系统树：从 system_root.xml 开始，每个 system_xxx.xml 只读取、解析一次
+type Model struct {
+	Name string
+	SLX  *SLX_Reader.Model // 其他文件（graphicalInterface.xml 等）仍从这里读取
+	Root *System
+}
+
+// RootFile 是每个模型第一层的 system 文件
+const RootFile = "system_root.xml"
+
+// HasRoot 判断 slx 内是否有 simulink/systems/system_root.xml
+func HasRoot(m *SLX_Reader.Model) bool {
+	_, err := fs.Stat(m.FS, path.Join(SLX_Reader.SystemsDir, RootFile))
+	return err == nil
+}
+
+// Load 从 system_root.xml 开始把整个模型解析成系统树
+// Block 的 SID 对应的 system_<SID>.xml 存在时挂到 Block.Child 上；同一个文件只解析一次
+func Load(m *SLX_Reader.Model) (*Model, error) {
+	loaded := make(map[string]*System)
+	root, err := loadSystem(m, RootFile, nil, loaded)
+	if err != nil {
+		return nil, err
+	}
+	return &Model{Name: m.Name, SLX: m, Root: root}, nil
+}
+
+// parent 为第一次引用该文件的 system，之后再引用同一文件不改变 Parent，保证 Parent 链不会成环
+func loadSystem(m *SLX_Reader.Model, file string, parent *System, loaded map[string]*System) (*System, error) {
+	if sys, ok := loaded[file]; ok {
+		return sys, nil
+	}
+
+	name := path.Join(SLX_Reader.SystemsDir, file)
+	data, err := fs.ReadFile(m.FS, name)
+	if err != nil {
+		return nil, fmt.Errorf("读取 XML 失败 [%s]: %w", m.Location(name), err)
+	}
+
+	sys := &System{File: file, Parent: parent}
+	if err := xml.Unmarshal(data, sys); err != nil {
+		return nil, fmt.Errorf("解析 XML 失败 [%s]: %w", m.Location(name), err)
+	}
+	// 先登记再解析子系统，防止异常模型里文件互相引用导致无限递归
+	loaded[file] = sys
+
+	for _, b := range sys.Blocks {
+		childFile := fmt.Sprintf("system_%s.xml", b.SID)
+		if _, err := fs.Stat(m.FS, path.Join(SLX_Reader.SystemsDir, childFile)); err != nil {
+			continue
+		}
+		if b.Child, err = loadSystem(m, childFile, sys, loaded); err != nil {
+			return nil, err
+		}
+	}
+	return sys, nil
+}
+
+// Prop 返回 P 标签 name 的值（去掉首尾空白），没有时返回 ""
+func (b *Block) Prop(name string) string {
+	for _, p := range b.Properties {
+		if p.Name == name {
+			return strings.TrimSpace(p.Value)
+		}
+	}
+	return ""
+}
+
+// SourceBlock 返回库链接 Block 的来源（如 "MyLib/Filters/LowPass"），其他 Block 返回 ""
+func (b *Block) SourceBlock() string {
+	if b.BlockType != BlockLibraryLink {
+		return ""
+	}
+	for _, p := range b.Properties {
+		if p.Name == "SourceBlock" {
+			return strings.TrimSpace(p.Value)
+		}
+	}
+	return ""
+}
+
+// ======================== 跨 slx 的模型库 ========================
+
+// Library 是输入目录中所有模型的系统树缓存，按模型名查找；可被多个 worker 同时使用
+//   - 被 ModelReference 引用的模型和库链接的库（RefTree / ResolveLink）一直缓存到分析结束，只读取、解析一次
+//   - 被分析的模型（Open / Tree）分析完后由 Release 释放，没有被引用过的从缓存中删除，内存不随输入模型数增长
+type Library struct {
+	lim   SLX_Reader.Limits
+	paths map[string]string // 模型名 → slx 路径
+	libs  map[string]string // 库名 → 库 slx 路径（inputs.library_dirs）
+	mu    sync.Mutex
+	items map[string]*libraryItem
+}
+
+type libraryItem struct {
+	once    sync.Once
+	slx     *SLX_Reader.Model
+	openErr error // 读取 slx 失败（包括被拒绝的不安全 slx）
+	tree    *Model
+	err     error // 没有 system_root.xml 时为 nil 且 tree 为 nil
+	shared  bool  // 通过 ModelReference / 库链接取得过，Release 时不删除
+}
+
+// NewLibrary 用 slxPaths（File_Utils_M1.FindSlxModels 的结果）和 libPaths（File_Utils_M1.FindLibraries 的结果）建立模型库，
+// 模型名、库名为 slx / mdl 文件名去掉扩展名
+func NewLibrary(slxPaths, libPaths []string, lim SLX_Reader.Limits) *Library {
+	return &Library{lim: lim, paths: byName(slxPaths), libs: byName(libPaths), items: make(map[string]*libraryItem)}
+}
+
+func byName(slxPaths []string) map[string]string {
+	m := make(map[string]string)
+	for _, p := range slxPaths {
+		base := filepath.Base(p)
+		m[strings.TrimSuffix(base, filepath.Ext(base))] = p
+	}
+	return m
+}
+
+// Lookup 返回模型名对应的 slx 路径；先精确匹配，再忽略大小写匹配（Windows 上文件名不区分大小写）
+func (l *Library) Lookup(name string) (string, bool) {
+	return lookup(l.paths, name)
+}
+
+func lookup(m map[string]string, name string) (string, bool) {
+	if p, ok := m[name]; ok {
+		return p, true
+	}
+	for n, p := range m {
+		if strings.EqualFold(n, name) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// Open 读取被分析模型的 slx，结果缓存到 Release 为止
+func (l *Library) Open(slxPath string) (*SLX_Reader.Model, error) {
+	it := l.load(slxPath, false)
+	return it.slx, it.openErr
+}
+
+// Tree 返回被分析模型的系统树，结果缓存到 Release 为止；slx 内没有 system_root.xml 时返回 nil, nil
+func (l *Library) Tree(slxPath string) (*Model, error) {
+	return l.load(slxPath, false).result()
+}
+
+// RefTree 返回被 ModelReference 引用的模型的系统树，结果一直缓存；slx 内没有 system_root.xml 时返回 nil, nil
+func (l *Library) RefTree(slxPath string) (*Model, error) {
+	return l.load(slxPath, true).result()
+}
+
+// Release 在被分析的模型分析完后调用：该模型没有被引用过时从缓存中删除（之后再被引用会重新读取）
+func (l *Library) Release(slxPath string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if it, ok := l.items[slxPath]; ok && !it.shared {
+		delete(l.items, slxPath)
+	}
+}
+
+func (it *libraryItem) result() (*Model, error) {
+	if it.openErr != nil {
+		return nil, it.openErr
+	}
+	return it.tree, it.err
+}
+
+// shared 为 true 表示通过引用取得，一直缓存
+func (l *Library) load(slxPath string, shared bool) *libraryItem {
+	l.mu.Lock()
+	it, ok := l.items[slxPath]
+	if !ok {
+		it = &libraryItem{}
+		l.items[slxPath] = it
+	}
+	it.shared = it.shared || shared
+	l.mu.Unlock()
+
+	it.once.Do(func() {
+		open := SLX_Reader.Open
+		if MDL_Reader.IsMDL(slxPath) {
+			open = MDL_Reader.Open // 文本格式模型转换成与 slx 相同的文件结构
+		}
+		if it.slx, it.openErr = open(slxPath, l.lim); it.openErr != nil {
+			return
+		}
+		if HasRoot(it.slx) {
+			it.tree, it.err = Load(it.slx)
+		}
+	})
+	return it
+}
+
+// ======================== 库链接 ========================
+
+// 库链接无法展开的原因，可用 errors.Is 判断
+var (
+	ErrLibraryNotFound = errors.New("库不在 inputs.library_dirs 中")
+	ErrSourceNotFound  = errors.New("库中没有该 Block")
+)
+
+// Link 是一个库链接解析后的结果
+//   - SourceBlock : 链接来源，如 "MyLib/Filters/LowPass"
+//   - Library     : 库名（SourceBlock 的第一段）
+//   - Model       : 库的系统树，Block 为其中的来源 Block（Block.Child 为库中的内容）；Err 不为空时都为 nil
+type Link struct {
+	SourceBlock string
+	Library     string
+	Model       *Model
+	Block       *Block
+	Err         error
+}
+
+// ResolveLink 在库中找到 SourceBlock 对应的 Block
+// 库先在 inputs.library_dirs 中找，再在输入模型中找；SourceBlock 中 "//" 表示名字里的 "/"
+func (l *Library) ResolveLink(sourceBlock string) *Link {
+	link := &Link{SourceBlock: sourceBlock}
+	parts := splitSourceBlock(sourceBlock)
+	link.Library = parts[0]
+
+	slxPath, ok := lookup(l.libs, link.Library)
+	if !ok {
+		if slxPath, ok = l.Lookup(link.Library); !ok {
+			link.Err = ErrLibraryNotFound
+			return link
+		}
+	}
+	tree, err := l.RefTree(slxPath)
+	if err == nil && tree == nil {
+		err = fmt.Errorf("没有 %s", RootFile)
+	}
+	if err != nil {
+		link.Err = err
+		return link
+	}
+
+	var blk *Block
+	sys := tree.Root
+	for _, name := range parts[1:] {
+		if sys == nil {
+			blk = nil
+			break
+		}
+		blk = findBlock(sys, name)
+		if blk == nil {
+			break
+		}
+		sys = blk.Child
+	}
+	if blk == nil {
+		link.Err = ErrSourceNotFound
+		return link
+	}
+	link.Model, link.Block = tree, blk
+	return link
+}
+
+// splitSourceBlock 按 "/" 拆分 SourceBlock，"//" 为名字中的 "/"
+func splitSourceBlock(s string) []string {
+	var parts []string
+	var cur strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '/' {
+			cur.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '/' {
+			cur.WriteByte('/')
+			i++
+			continue
+		}
+		parts = append(parts, cur.String())
+		cur.Reset()
+	}
+	return append(parts, cur.String())
+}
+
+// findBlock 按名字找 Block，换行、多余空格不影响比较
+func findBlock(sys *System, name string) *Block {
+	name = strings.Join(strings.Fields(name), " ")
+	for _, b := range sys.Blocks {
+		if strings.Join(strings.Fields(b.Name), " ") == name {
+			return b
+		}
+	}
+	return nil
+}
@@ -0,0 +1,363 @@
+package Variant_Analysis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"FCU_Tools/Config"
+	"FCU_Tools/M1/System_Tree"
+)
+
+// Variant 相关的 BlockType / 属性
+const (
+	BlockVariantSource = "VariantSource"
+	BlockVariantSink   = "VariantSink"
+
+	defaultChoice = "(default)" // VariantControl 为 (default) 的 choice 在其他 choice 都不激活时激活
+)
+
+// Selector 按 metrics.m1.variants 决定 Variant Subsystem / Variant Source·Sink 中哪些部分计入 M1
+//   - Mode     : Config.VariantAll / VariantActive / VariantWorstCase
+//   - Controls : 变体控制变量的值（活动的 variant 配置）
+type Selector struct {
+	Mode     string
+	Controls map[string]string
+}
+
+// NewSelector 由配置生成 Selector
+func NewSelector(opts Config.M1VariantOptions) Selector {
+	return Selector{Mode: opts.Mode, Controls: opts.Controls}
+}
+
+// IsVariantSubsystem 判断 b 是否为 Variant Subsystem（SubSystem 且 Variant=on）
+func IsVariantSubsystem(b *System_Tree.Block) bool {
+	return b != nil && b.BlockType == "SubSystem" && b.Prop("Variant") == "on"
+}
+
+// IsVariantPort 判断 b 是否为 Variant Source / Variant Sink
+func IsVariantPort(b *System_Tree.Block) bool {
+	return b != nil && (b.BlockType == BlockVariantSource || b.BlockType == BlockVariantSink)
+}
+
+// Choices 返回 Variant Subsystem 的各个 choice（下一层 system 中的 SubSystem / ModelReference）
+func Choices(vss *System_Tree.Block) []*System_Tree.Block {
+	var choices []*System_Tree.Block
+	if vss == nil || vss.Child == nil {
+		return choices
+	}
+	for _, b := range vss.Child.Blocks {
+		if b.BlockType == "SubSystem" || b.BlockType == "ModelReference" {
+			choices = append(choices, b)
+		}
+	}
+	return choices
+}
+
+// ActiveChoice 返回 Variant Subsystem 中激活的 choice
+//   - label 模式（VariantControlMode=label）：VariantControl 等于 LabelModeActiveChoice 的 choice
+//   - 旧版本的 OverrideUsingVariant：VariantControl 或名字等于它的 choice
+//   - 表达式模式：用 Controls 计算各 choice 的 VariantControl，只能有一个为真；都不为真时取 (default)
+func (s Selector) ActiveChoice(vss *System_Tree.Block) (*System_Tree.Block, error) {
+	choices := Choices(vss)
+	conds := make([]string, len(choices))
+	for i, c := range choices {
+		conds[i] = c.Prop("VariantControl")
+	}
+	i, err := s.active(vss, conds, func(i int) string { return choices[i].Name })
+	if err != nil {
+		return nil, err
+	}
+	return choices[i], nil
+}
+
+// ActivePort 返回 Variant Source（输入）/ Variant Sink（输出）激活的端口序号（从 1 开始）
+// 各端口的条件在 VariantControls 中，如 {'V==1','V==2'}
+func (s Selector) ActivePort(b *System_Tree.Block) (int, error) {
+	conds := splitControls(b.Prop("VariantControls"))
+	i, err := s.active(b, conds, func(i int) string { return strconv.Itoa(i + 1) })
+	if err != nil {
+		return 0, err
+	}
+	return i + 1, nil
+}
+
+// active 返回 conds 中激活的下标；name 用于错误信息
+func (s Selector) active(b *System_Tree.Block, conds []string, name func(int) string) (int, error) {
+	if len(conds) == 0 {
+		return 0, fmt.Errorf("没有 variant choice")
+	}
+
+	label := ""
+	if b.Prop("VariantControlMode") == "label" {
+		label = b.Prop("LabelModeActiveChoice")
+	} else {
+		label = b.Prop("OverrideUsingVariant")
+	}
+	if label != "" {
+		for i, c := range conds {
+			if c == label || name(i) == label {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("找不到激活的 choice %q", label)
+	}
+
+	found, def := -1, -1
+	for i, c := range conds {
+		if c == defaultChoice {
+			def = i
+			continue
+		}
+		if c == "" {
+			continue
+		}
+		ok, err := Eval(c, s.Controls)
+		if err != nil {
+			return 0, fmt.Errorf("choice %s 的条件 %q: %w", name(i), c, err)
+		}
+		if !ok {
+			continue
+		}
+		if found >= 0 {
+			return 0, fmt.Errorf("choice %s 和 %s 同时激活", name(found), name(i))
+		}
+		found = i
+	}
+	if found < 0 {
+		found = def
+	}
+	if found < 0 {
+		return 0, fmt.Errorf("没有激活的 choice")
+	}
+	return found, nil
+}
+
+// "{'V==1','V==2'}" → ["V==1", "V==2"]
+func splitControls(s string) []string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	var out []string
+	var cur strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			out = append(out, strings.TrimSpace(cur.String()))
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if t := strings.TrimSpace(cur.String()); t != "" || len(out) > 0 {
+		out = append(out, t)
+	}
+	return out
+}
+
+// ======================== 条件表达式 ========================
+
+// Eval 计算 VariantControl 条件，如 "VAR_TRIM == 2 && (MODE ~= Mode.Eco || ~DEBUG)"
+//   - 运算符：== ~= != < <= > >= && || ~ ! 和括号
+//   - 标识符取 controls 中的值；没有设置时，带 "." 的（枚举值 Mode.Eco）按字面值处理，其他报错
+//   - 两边都是数字时按数值比较，否则按字符串比较；单独的值 0 / false / 空为假
+//   - 与 MATLAB 的逻辑值相同，比较 / 逻辑运算的结果和 true / false 为 1 / 0（如 ~A == 1）
+func Eval(cond string, controls map[string]string) (bool, error) {
+	p := &parser{controls: controls}
+	if err := p.tokenize(cond); err != nil {
+		return false, err
+	}
+	v, err := p.or()
+	if err != nil {
+		return false, err
+	}
+	if p.pos < len(p.tokens) {
+		return false, fmt.Errorf("多余的 %q", p.tokens[p.pos])
+	}
+	return truthy(v), nil
+}
+
+type parser struct {
+	tokens   []string
+	pos      int
+	controls map[string]string
+}
+
+func (p *parser) tokenize(s string) error {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case strings.ContainsRune("=~!<>&|", rune(c)):
+			j := i + 1
+			if j < len(s) && strings.ContainsRune("=&|", rune(s[j])) {
+				j++
+			}
+			p.tokens = append(p.tokens, s[i:j])
+			i = j
+		case c == '(' || c == ')':
+			p.tokens = append(p.tokens, s[i:i+1])
+			i++
+		case c == '\'' || c == '"':
+			j := strings.IndexByte(s[i+1:], c)
+			if j < 0 {
+				return fmt.Errorf("引号不成对")
+			}
+			p.tokens = append(p.tokens, s[i:i+j+2])
+			i += j + 2
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n=~!<>&|()'\"", rune(s[j])) {
+				j++
+			}
+			p.tokens = append(p.tokens, s[i:j])
+			i = j
+		}
+	}
+	if len(p.tokens) == 0 {
+		return fmt.Errorf("条件为空")
+	}
+	return nil
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) or() (string, error) {
+	l, err := p.and()
+	for err == nil && p.peek() == "||" {
+		p.pos++
+		var r string
+		if r, err = p.and(); err == nil {
+			l = boolValue(truthy(l) || truthy(r))
+		}
+	}
+	return l, err
+}
+
+func (p *parser) and() (string, error) {
+	l, err := p.compare()
+	for err == nil && p.peek() == "&&" {
+		p.pos++
+		var r string
+		if r, err = p.compare(); err == nil {
+			l = boolValue(truthy(l) && truthy(r))
+		}
+	}
+	return l, err
+}
+
+func (p *parser) compare() (string, error) {
+	l, err := p.unary()
+	if err != nil {
+		return l, err
+	}
+	switch op := p.peek(); op {
+	case "==", "~=", "!=", "<", "<=", ">", ">=":
+		p.pos++
+		r, err := p.unary()
+		if err != nil {
+			return r, err
+		}
+		return boolValue(compare(l, op, r)), nil
+	}
+	return l, nil
+}
+
+func (p *parser) unary() (string, error) {
+	switch t := p.peek(); t {
+	case "~", "!":
+		p.pos++
+		v, err := p.unary()
+		return boolValue(!truthy(v)), err
+	case "(":
+		p.pos++
+		v, err := p.or()
+		if err != nil {
+			return v, err
+		}
+		if p.peek() != ")" {
+			return v, fmt.Errorf("缺少 \")\"")
+		}
+		p.pos++
+		return v, nil
+	case "":
+		return "", fmt.Errorf("条件不完整")
+	default:
+		p.pos++
+		return p.value(t)
+	}
+}
+
+// value 把一个操作数变成值：引号内的字符串、数字、true/false、控制变量或枚举值
+func (p *parser) value(t string) (string, error) {
+	switch {
+	case strings.HasPrefix(t, "'") || strings.HasPrefix(t, "\""):
+		return t[1 : len(t)-1], nil
+	case strings.ContainsRune("=~!<>&|)", rune(t[0])):
+		return "", fmt.Errorf("意外的 %q", t)
+	case t == "true" || t == "false":
+		return boolValue(t == "true"), nil
+	}
+	if _, err := strconv.ParseFloat(t, 64); err == nil {
+		return t, nil
+	}
+	if v, ok := p.controls[t]; ok {
+		return strings.TrimSpace(v), nil
+	}
+	if strings.Contains(t, ".") {
+		return t, nil
+	}
+	return "", fmt.Errorf("没有设置变体控制变量 %s（metrics.m1.variants.controls）", t)
+}
+
+func compare(l, op, r string) bool {
+	lf, errL := strconv.ParseFloat(l, 64)
+	rf, errR := strconv.ParseFloat(r, 64)
+	c := strings.Compare(l, r)
+	if errL == nil && errR == nil {
+		switch {
+		case lf < rf:
+			c = -1
+		case lf > rf:
+			c = 1
+		default:
+			c = 0
+		}
+	}
+	switch op {
+	case "==":
+		return c == 0
+	case "~=", "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+func truthy(v string) bool {
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f != 0
+	}
+	return v != "" && v != "false"
+}
+
+func boolValue(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
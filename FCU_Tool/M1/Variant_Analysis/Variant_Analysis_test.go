@@ -0,0 +1,170 @@
+package Variant_Analysis
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"FCU_Tools/M1/System_Tree"
+)
+
+func TestEval(t *testing.T) {
+	controls := map[string]string{"A": "1", "B": "0", "C": "0", "V": "9", "S": "abc", "MODE": "Mode.Eco", "Z": " 2 "}
+	tests := []struct {
+		name string
+		cond string
+		want bool
+	}{
+		// && 优先于 ||；比较优先于 && / ||
+		{"&& 优先", "A == 1 || B == 1 && C == 1", true},
+		{"&& 优先（左边为假）", "A == 0 || B == 0 && C == 1", false},
+		{"括号", "(A == 1 || B == 1) && C == 1", false},
+		{"~ 作用于操作数", "~B == 1", true},
+		{"~ 作用于括号", "~(A == 1)", false},
+		{"! 与 ~ 相同", "!B && A", true},
+
+		{"~=", "A ~= 2", true},
+		{"!=", "A != 1", false},
+
+		// 两边都是数字时按数值比较
+		{"数值相等", "A == 1.0", true},
+		{"数值大小", "V < 10", true},
+		{"数值 >=", "V >= 9", true},
+		{"值两边空白", "Z == 2", true},
+		// 否则按字符串比较
+		{"字符串相等", "S == 'abc'", true},
+		{"双引号字符串", `S == "abd"`, false},
+		{"字符串大小", "S < 'b'", true},
+		{"数字与字符串", "A == 'x'", false},
+
+		{"枚举值（已设置）", "MODE == Mode.Eco", true},
+		{"枚举值（字面值）", "MODE ~= Mode.Normal", true},
+		{"true 字面值", "true == 1", true},
+		{"单独的 0 为假", "B", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Eval(tt.cond, controls)
+			if err != nil {
+				t.Fatalf("Eval(%q): %v", tt.cond, err)
+			}
+			if got != tt.want {
+				t.Errorf("Eval(%q) = %v，期望 %v", tt.cond, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		cond string
+		want string // 错误信息中应包含的内容
+	}{
+		{"UNSET == 1", "UNSET"},
+		{"A == 1 && UNSET", "UNSET"},
+		{"(A == 1", ")"},
+		{"A ==", "不完整"},
+		{"A == 'x", "引号"},
+		{"A == 1 B", "多余"},
+		{"", "为空"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cond, func(t *testing.T) {
+			_, err := Eval(tt.cond, map[string]string{"A": "1"})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Eval(%q) 错误 = %v，期望包含 %q", tt.cond, err, tt.want)
+			}
+		})
+	}
+}
+
+// variantSubsystem 生成 Variant Subsystem：choices 为各 choice 的名字和 VariantControl，props 为 Variant Subsystem 自身的属性
+func variantSubsystem(choices [][2]string, props ...string) *System_Tree.Block {
+	vss := &System_Tree.Block{BlockType: "SubSystem", Name: "VSS", Child: &System_Tree.System{}}
+	vss.Properties = append(vss.Properties, System_Tree.P{Name: "Variant", Value: "on"})
+	for i := 0; i+1 < len(props); i += 2 {
+		vss.Properties = append(vss.Properties, System_Tree.P{Name: props[i], Value: props[i+1]})
+	}
+	for _, c := range choices {
+		vss.Child.Blocks = append(vss.Child.Blocks, &System_Tree.Block{
+			BlockType:  "SubSystem",
+			Name:       c[0],
+			Properties: []System_Tree.P{{Name: "VariantControl", Value: c[1]}},
+		})
+	}
+	// 不是 choice 的 Block 不参与选择
+	vss.Child.Blocks = append(vss.Child.Blocks, &System_Tree.Block{BlockType: "Inport", Name: "In1"})
+	return vss
+}
+
+func TestActiveChoice(t *testing.T) {
+	exprChoices := [][2]string{{"One", "V == 1"}, {"Two", "V == 2"}, {"Other", "(default)"}}
+	tests := []struct {
+		name     string
+		vss      *System_Tree.Block
+		controls map[string]string
+		want     string // 激活的 choice 名
+		err      string // 期望的错误（为空时不应出错）
+	}{
+		{"表达式", variantSubsystem(exprChoices), map[string]string{"V": "2"}, "Two", ""},
+		{"(default)", variantSubsystem(exprChoices), map[string]string{"V": "3"}, "Other", ""},
+		{"两个 choice 同时激活", variantSubsystem([][2]string{{"Low", "V < 5"}, {"Pos", "V > 0"}}), map[string]string{"V": "1"}, "", "同时激活"},
+		{"没有激活的 choice", variantSubsystem([][2]string{{"One", "V == 1"}}), map[string]string{"V": "2"}, "", "没有激活"},
+		{"变量没有设置", variantSubsystem(exprChoices), nil, "", "没有设置变体控制变量 V"},
+		{"空条件跳过", variantSubsystem([][2]string{{"Empty", ""}, {"One", "V == 1"}}), map[string]string{"V": "1"}, "One", ""},
+
+		// label 模式按 LabelModeActiveChoice 选择，不计算表达式
+		{"label（VariantControl）", variantSubsystem([][2]string{{"A", "lblA"}, {"B", "lblB"}}, "VariantControlMode", "label", "LabelModeActiveChoice", "lblB"), nil, "B", ""},
+		{"label（名字）", variantSubsystem([][2]string{{"A", "lblA"}, {"B", "lblB"}}, "VariantControlMode", "label", "LabelModeActiveChoice", "A"), nil, "A", ""},
+		{"label 找不到", variantSubsystem([][2]string{{"A", "lblA"}}, "VariantControlMode", "label", "LabelModeActiveChoice", "lblX"), nil, "", "lblX"},
+		{"OverrideUsingVariant", variantSubsystem(exprChoices, "OverrideUsingVariant", "V == 2"), map[string]string{"V": "1"}, "Two", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Selector{Controls: tt.controls}.ActiveChoice(tt.vss)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("错误 = %v，期望包含 %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ActiveChoice: %v", err)
+			}
+			if got.Name != tt.want {
+				t.Errorf("激活的 choice = %s，期望 %s", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestActivePort(t *testing.T) {
+	b := &System_Tree.Block{BlockType: BlockVariantSource, Properties: []System_Tree.P{
+		{Name: "VariantControls", Value: "{'V==1','V==2','(default)'}"},
+	}}
+	for v, want := range map[string]int{"1": 1, "2": 2, "7": 3} {
+		got, err := Selector{Controls: map[string]string{"V": v}}.ActivePort(b)
+		if err != nil || got != want {
+			t.Errorf("V=%s: ActivePort = %d, %v，期望 %d", v, got, err, want)
+		}
+	}
+}
+
+func TestSplitControls(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"{'V==1','V==2'}", []string{"V==1", "V==2"}},
+		{" { 'A==1 && B==2' , '(default)' } ", []string{"A==1 && B==2", "(default)"}},
+		{"{'x,y'}", []string{"x,y"}},
+		{"{'V==1',''}", []string{"V==1", ""}},
+		{"{}", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := splitControls(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitControls(%q) = %q，期望 %q", tt.in, got, tt.want)
+		}
+	}
+}
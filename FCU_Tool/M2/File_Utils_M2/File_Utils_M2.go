@@ -3,95 +3,66 @@ package File_Utils_M2
 import (
 	"encoding/csv"
 	"encoding/json"
-	"encoding/xml"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"path/filepath"
 	"regexp"
 	"strings"
 
+	"FCU_Tools/Config"
+	"FCU_Tools/LDI"
 	"FCU_Tools/Public_data"
 )
 
-// CheckAndSetM2InputPath 检查指定目录下是否包含 M2 所需的输入文件
-// （complexity.json 与 rq_versus_component.csv），并在 Public_data 中保存其路径。
+// CheckM2InputPath 检查配置中 M2 所需的输入文件
+// （complexity.json 与 rq_versus_component.csv）是否存在。
 //
 // 流程：
-//   1) 拼接 dir/complexity.json 与 dir/rq_versus_component.csv。
+//   1) 读取 cfg.Inputs.ComplexityJson 与 cfg.Inputs.RqVersusComponentCsv。
 //   2) 调用 os.Stat 确认文件存在；缺失则返回错误。
-//   3) 将路径分别存入 Public_data.M2ComplexityJsonPath、Public_data.M2RqExcelPath。
-func CheckAndSetM2InputPath(dir string) error {
-	complexity := filepath.Join(dir, "complexity.json")
-	rqCsv := filepath.Join(dir, "rq_versus_component.csv")
+func CheckM2InputPath(cfg *Config.Config) error {
+	complexity := cfg.Inputs.ComplexityJson
+	rqCsv := cfg.Inputs.RqVersusComponentCsv
 
-	if _, err := os.Stat(complexity); os.IsNotExist(err) {
+	if _, err := os.Stat(complexity); err != nil {
 		return fmt.Errorf("complexity.json을 찾을 수 없습니다: %s", complexity)
 	}
-	if _, err := os.Stat(rqCsv); os.IsNotExist(err) {
+	if _, err := os.Stat(rqCsv); err != nil {
 		return fmt.Errorf("rq_versus_component.csv을 찾을 수 없습니다: %s", rqCsv)
 	}
-
-	Public_data.M2ComplexityJsonPath = complexity
-	// 변수명은 기존 그대로 사용하지만, 이제 CSV 경로를 담는다.
-	Public_data.M2RqExcelPath = rqCsv
 	return nil
 }
 
-// PrepareM2OutputDir 准备 M2 的输出目录。
-//
-// 流程：
-//   1) 获取当前工作目录。
-//   2) 拼接 <工作目录>/M2/output 路径。
-//   3) 若已存在 output/ 则先删除再新建。
-//   4) 将路径保存到 Public_data.M2OutputlPath。
-func PrepareM2OutputDir() error {
-	basePath, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("작업 디렉토리를 가져오지 못했습니다: %v", err)
-	}
-	outputPath := filepath.Join(basePath, "M2", "output")
-
-	if _, err := os.Stat(outputPath); err == nil {
-		if err := os.RemoveAll(outputPath); err != nil {
-			return fmt.Errorf("이전 output 디렉토리를 삭제하지 못했습니다: %v", err)
-		}
-	}
-
-	if err := os.MkdirAll(outputPath, 0755); err != nil {
-		return fmt.Errorf("output 디렉터리를 만드는 데 실패했습니다: %v", err)
-	}
-
-	// Public_data에 경로 저장 변수
-	Public_data.M2OutputlPath = outputPath
-
-	return nil
+// PrepareM2OutputDir 准备 M2 的输出目录 <WorkDir>/M2/output（已存在则先删除再新建）。
+func PrepareM2OutputDir(cfg *Config.Config) error {
+	return Public_data.PrepareStageOutputDir(cfg.StageOutputDir("M2"))
 }
 
-// GenerateM2LDIXml complexity.json과 rq_versus_component.csv를 읽어 M2.ldi.xml을 생성한다.
+// GenerateM2LDIXml complexity.json과 rq_versus_component.csv를 읽어 M2 지표(coverage.m2) LDI 문서를 만든다.
 //
 // 프로세스:
 //   1) complexity.json을 읽어 map[string]float64로 파싱 (모듈명 → 복잡도 값).
 //   2) rq_versus_component.csv를 열고 모든 행을 읽어 Req 이름을 컴포넌트명에 매핑.
-//   3) 정규식을 이용해 JSON key의 접두어([REQ] 형태)를 매칭하고,
+//   3) 정규식(cfg.Metrics.M2.RequirementPattern, 기본값은 [REQ] 형태)을 이용해 JSON key의 접두어를 매칭하고,
 //      excelMap을 활용해 컴포넌트명으로 매핑.
+//   4) LDI.Document에 coverage.m2 속성을 기록하여 돌려준다 (M2/output/M2.ldi.xml 저장은 Stage_Graph가 한다).
 //
-func GenerateM2LDIXml() error {
+func GenerateM2LDIXml(cfg *Config.Config) (*LDI.Document, error) {
 	// complexity.json 읽기
-	data, err := ioutil.ReadFile(Public_data.M2ComplexityJsonPath)
+	data, err := ioutil.ReadFile(cfg.Inputs.ComplexityJson)
 	if err != nil {
-		return fmt.Errorf("complexity.json 읽기 실패: %v", err)
+		return nil, fmt.Errorf("complexity.json 읽기 실패: %v", err)
 	}
 
 	var jsonMap map[string]float64
 	if err := json.Unmarshal(data, &jsonMap); err != nil {
-		return fmt.Errorf("complexity.json 살펴보기 실패: %v", err)
+		return nil, fmt.Errorf("complexity.json 살펴보기 실패: %v", err)
 	}
 
 	// CSV 파일 열기 (rq_versus_component.csv)
-	f, err := os.Open(Public_data.M2RqExcelPath)
+	f, err := os.Open(cfg.Inputs.RqVersusComponentCsv)
 	if err != nil {
-		return fmt.Errorf("CSV 열기 실패: %v", err)
+		return nil, fmt.Errorf("CSV 열기 실패: %v", err)
 	}
 	defer f.Close()
 
@@ -101,7 +72,7 @@ func GenerateM2LDIXml() error {
 
 	excelRows, err := r.ReadAll()
 	if err != nil {
-		return fmt.Errorf("CSV 행 읽기 실패: %v", err)
+		return nil, fmt.Errorf("CSV 행 읽기 실패: %v", err)
 	}
 
 	excelMap := make(map[string]string)
@@ -111,47 +82,18 @@ func GenerateM2LDIXml() error {
 		}
 	}
 
-	type Property struct {
-		XMLName xml.Name `xml:"property"`
-		Name    string   `xml:"name,attr"`
-		Value   string   `xml:",chardata"`
-	}
-	type Element struct {
-		XMLName  xml.Name  `xml:"element"`
-		Name     string    `xml:"name,attr"`
-		Property []Property `xml:"property"`
-	}
-	type Root struct {
-		XMLName xml.Name  `xml:"ldi"`
-		Items   []Element `xml:"element"`
+	doc := LDI.New()
+	re, err := regexp.Compile(cfg.Metrics.M2.RequirementPattern)
+	if err != nil {
+		return nil, fmt.Errorf("requirement_pattern 정규식 오류: %v", err)
 	}
-
-	var result Root
-	re := regexp.MustCompile(`^\[[^\]]+\]`)
 	for key, val := range jsonMap {
 		match := re.FindString(key)
 		if compName, ok := excelMap[match]; ok {
-			element := Element{
-				Name: strings.ReplaceAll(compName, ".", ""),
-				Property: []Property{{
-					Name:  "coverage.m2",
-					Value: fmt.Sprintf("%v", val),
-				}},
-			}
-			result.Items = append(result.Items, element)
+			doc.AddElement(strings.ReplaceAll(compName, ".", "")).
+				SetProperty("coverage.m2", fmt.Sprintf("%v", val))
 		}
 	}
 
-	outputFile := filepath.Join(Public_data.M2OutputlPath, "M2.ldi.xml")
-	out, err := xml.MarshalIndent(result, "  ", "    ")
-	if err != nil {
-		return fmt.Errorf("XML 직렬화 실패: %v", err)
-	}
-
-	header := []byte(xml.Header)
-	if err := ioutil.WriteFile(outputFile, append(header, out...), 0644); err != nil {
-		return fmt.Errorf("ldi.xml 쓰기 실패: %v", err)
-	}
-
-	return nil
+	return doc, nil
 }
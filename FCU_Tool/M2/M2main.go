@@ -2,48 +2,52 @@ package M2main
 
 import (
 	"fmt"
-	"bufio"
-	"os"
-	"strings"
+	"FCU_Tools/Config"
+	"FCU_Tools/Dependency_Graph"
+	"FCU_Tools/LDI"
 	"FCU_Tools/M2/File_Utils_M2"
-	"FCU_Tools/M2/LDI_M2_Create"
+	"FCU_Tools/Metric"
 )
 
-// M2_main은 M2 프로세스의 총 진입점으로,
-// 사용자 입력 경로를 읽고, M2 입력 파일을 확인하며, 출력 디렉터리를 준비하고,
-// M2 LDI 파일을 생성한 뒤 그 지표를 메인 LDI에 병합한다.
-func M2_main() {
-	//   1) 표준 입력에서 사용자가 지정한 디렉터리 경로를 읽는다
-	//      (complexity.json과 rq_versus_component.csv를 포함해야 함).
+func init() {
+	Metric.Register(m2{})
+}
 
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("필요한 M2 파일(complexity.json 및 rq_versus_component.csv)이 포함된 폴더 경로를 입력하십시오: ")
-	dirInput, _ := reader.ReadString('\n')
-	dir := strings.TrimSpace(dirInput)
+// m2 는 M2(요구사항 복잡도) 지표이며, init 에서 Metric 레지스트리에 등록된다.
+type m2 struct{}
 
-	//   2) File_Utils_M2.CheckAndSetM2InputPath를 호출하여
-	//      디렉터리를 검증하고 입력 파일 경로를 저장한다.
+func (m2) Name() string { return "M2" }
 
-	if err := File_Utils_M2.CheckAndSetM2InputPath(dir); err != nil {
-		fmt.Println("M2 가져오기 파일 설정 실패: ", err)
-		return
-	}
+func (m2) Inputs() []*Metric.Input {
+	return []*Metric.Input{Metric.ComplexityJson, Metric.RqVersusComponentCsv}
+}
 
-	//   3) File_Utils_M2.PrepareM2OutputDir를 호출하여
-	//      출력 디렉터리를 삭제하고 다시 생성한다.
+func (m2) Enabled(cfg *Config.Config) bool { return cfg.Metrics.M2.Enabled }
 
-	if err := File_Utils_M2.PrepareM2OutputDir(); err != nil {
-		fmt.Println("M2 출력 디렉토리 준비 실패：", err)
-		return
+func (m2) Compute(ctx *Metric.Context, model *Dependency_Graph.Graph) (*LDI.Document, error) {
+	return M2_compute(ctx.Config)
+}
+
+// M2_compute는 M2 지표 계산 단계로,
+// 설정(cfg)의 M2 입력 파일을 확인하고, 출력 디렉터리를 준비하며,
+// M2 지표 LDI 문서를 돌려준다. 파일 저장과 메인 LDI 병합은 Stage_Graph가 따로 수행한다.
+func M2_compute(cfg *Config.Config) (*LDI.Document, error) {
+	//   1) File_Utils_M2.CheckM2InputPath를 호출하여
+	//      complexity.json과 rq_versus_component.csv가 있는지 검증한다.
+
+	if err := File_Utils_M2.CheckM2InputPath(cfg); err != nil {
+		return nil, fmt.Errorf("M2 가져오기 파일 설정 실패: %v", err)
 	}
 
-	//   4) File_Utils_M2.GenerateM2LDIXml을 호출하여
-	//      M2/output/M2.ldi.xml을 생성한다.
+	//   2) File_Utils_M2.PrepareM2OutputDir를 호출하여
+	//      출력 디렉터리를 삭제하고 다시 생성한다.
 
-	File_Utils_M2.GenerateM2LDIXml()
+	if err := File_Utils_M2.PrepareM2OutputDir(cfg); err != nil {
+		return nil, fmt.Errorf("M2 출력 디렉토리 준비 실패：%v", err)
+	}
 
-	//   5) LDI_M2_Create.MergeM2ToMainLDI를 호출하여
-	//      coverage.m2를 메인 LDI에 병합한다.
+	//   3) File_Utils_M2.GenerateM2LDIXml을 호출하여
+	//      coverage.m2 LDI 문서를 만든다.
 
-	LDI_M2_Create.MergeM2ToMainLDI()
+	return File_Utils_M2.GenerateM2LDIXml(cfg)
 }
@@ -2,37 +2,43 @@ package M3main
 
 import (
 	"fmt"
-	"bufio"
-	"os"
-	"strings"
+	"FCU_Tools/Config"
+	"FCU_Tools/Dependency_Graph"
+	"FCU_Tools/LDI"
 	"FCU_Tools/M3/File_Utils_M3"
-	"FCU_Tools/M3/LDI_M3_Create"
+	"FCU_Tools/Metric"
 )
 
-// M3_main 은 M3 프로세스의 진입점이다: 입력을 검사하고 출력 디렉터리를 준비하며,
-// M3 LDI 파일을 생성하고 그 지표를 주 LDI에 병합한다.
-func M3_main() {
-	//   1) 사용자에게 component_info.csv가 포함된 디렉터리를 입력하도록 안내한다.
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("필요한 M3 파일(component_info.csv)이 포함된 폴더 경로를 입력하십시오: ")
-	dirInput, _ := reader.ReadString('\n')
-	dir := strings.TrimSpace(dirInput)
-
-	//   2) File_Utils_M3.CheckAndSetM2InputPath를 호출하여 입력 파일 경로를 검증하고 저장한다.
-	if err := File_Utils_M3.CheckAndSetM2InputPath(dir); err != nil {
-		fmt.Println("M3 가져오기 파일 설정 실패: ", err)
-		return
-	}
+func init() {
+	Metric.Register(m3{})
+}
+
+// m3 는 M3(레이어 위반) 지표이며, init 에서 Metric 레지스트리에 등록된다.
+type m3 struct{}
+
+func (m3) Name() string { return "M3" }
+
+func (m3) Inputs() []*Metric.Input { return []*Metric.Input{Metric.AswCsv, Metric.ComponentInfoCsv} }
 
-	//   3) File_Utils_M3.PrepareM2OutputDir를 호출하여 출력 디렉터리를 삭제하고 다시 생성한다.
-	if err := File_Utils_M3.PrepareM2OutputDir(); err != nil {
-		fmt.Println("M3 출력 디렉토리 준비 실패：", err)
-		return
+func (m3) Enabled(cfg *Config.Config) bool { return cfg.Metrics.M3.Enabled }
+
+func (m3) Compute(ctx *Metric.Context, model *Dependency_Graph.Graph) (*LDI.Document, error) {
+	return M3_compute(ctx.Config, model)
+}
+
+// M3_compute 는 M3 지표 계산 단계이다: 입력을 검사하고 출력 디렉터리를 준비하며,
+// M3 지표 LDI 문서를 돌려준다. 파일 저장과 주 LDI 병합은 Stage_Graph가 따로 수행한다.
+func M3_compute(cfg *Config.Config, g *Dependency_Graph.Graph) (*LDI.Document, error) {
+	//   1) File_Utils_M3.CheckM3InputPath를 호출하여 설정된 component_info.csv를 검증한다.
+	if err := File_Utils_M3.CheckM3InputPath(cfg); err != nil {
+		return nil, fmt.Errorf("M3 가져오기 파일 설정 실패: %v", err)
 	}
 
-	//   4) File_Utils_M3.GenerateM3LDIXml을 호출하여 M3/output/M3.ldi.xml과 M3.txt를 생성한다.
-	File_Utils_M3.GenerateM3LDIXml()
+	//   2) File_Utils_M3.PrepareM2OutputDir를 호출하여 출력 디렉터리를 삭제하고 다시 생성한다.
+	if err := File_Utils_M3.PrepareM2OutputDir(cfg); err != nil {
+		return nil, fmt.Errorf("M3 출력 디렉토리 준비 실패：%v", err)
+	}
 
-	//   5) LDI_M3_Create.MergeM3ToMainLDI를 호출하여 M3 지표를 주 LDI 파일에 병합한다.
-	LDI_M3_Create.MergeM3ToMainLDI()
+	//   3) File_Utils_M3.GenerateM3LDIXml을 호출하여 M3 LDI 문서와 M3/output/M3.txt를 만든다.
+	return File_Utils_M3.GenerateM3LDIXml(cfg, g)
 }
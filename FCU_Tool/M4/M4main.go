@@ -2,23 +2,40 @@ package M4main
 
 import (
 	"fmt"
+	"FCU_Tools/Config"
+	"FCU_Tools/Dependency_Graph"
+	"FCU_Tools/LDI"
 	"FCU_Tools/M4/File_Utils_M4"
-	"FCU_Tools/M4/LDI_M4_Create"
+	"FCU_Tools/Metric"
 )
-// M4_main 은 M4 지표 계산과 병합의 총 진입점이다.  
-func M4_main() {
+
+func init() {
+	Metric.Register(m4{})
+}
+
+// m4 는 M4(연결 규칙 위반) 지표이며, init 에서 Metric 레지스트리에 등록된다.
+type m4 struct{}
+
+func (m4) Name() string { return "M4" }
+
+func (m4) Inputs() []*Metric.Input { return []*Metric.Input{Metric.AswCsv, Metric.ComponentInfoCsv} }
+
+func (m4) Enabled(cfg *Config.Config) bool { return cfg.Metrics.M4.Enabled }
+
+func (m4) Compute(ctx *Metric.Context, model *Dependency_Graph.Graph) (*LDI.Document, error) {
+	return M4_compute(ctx.Config, model)
+}
+
+// M4_compute 는 M4 지표 계산 단계이다. 파일 저장과 주 LDI 병합은 Stage_Graph가 따로 수행한다.
+func M4_compute(cfg *Config.Config, g *Dependency_Graph.Graph) (*LDI.Document, error) {
 
 	//   1) File_Utils_M4.PrepareM2OutputDir를 호출하여 출력 디렉터리를 초기화한다.  
- 	if err := File_Utils_M4.PrepareM2OutputDir(); err != nil {
-		fmt.Println("M4 출력 디렉토리 준비 실패：", err)
-		return
+ 	if err := File_Utils_M4.PrepareM2OutputDir(cfg); err != nil {
+		return nil, fmt.Errorf("M4 출력 디렉토리 준비 실패：%v", err)
 	}
 
-	//   2) File_Utils_M4.GenerateM4LDIXml을 호출하여 지표를 계산하고 M4.ldi.xml과 M4.txt를 생성한다.  
-	File_Utils_M4.GenerateM4LDIXml()
-
-	//   3) LDI_M4_Create.MergeM4ToMainLDI를 호출하여 결과를 주 LDI 파일 result.ldi.xml에 병합한다.  
-	LDI_M4_Create.MergeM4ToMainLDI()
+	//   2) File_Utils_M4.GenerateM4LDIXml을 호출하여 지표를 계산하고 M4 LDI 문서와 M4.txt를 만든다.  
+	return File_Utils_M4.GenerateM4LDIXml(cfg, g)
 
 
-}
\ No newline at end of file
+}
@@ -2,24 +2,39 @@ package M5main
 
 import (
 	"fmt"
+	"FCU_Tools/Config"
+	"FCU_Tools/Dependency_Graph"
+	"FCU_Tools/LDI"
 	"FCU_Tools/M5/File_Utils_M5"
-	"FCU_Tools/M5/LDI_M5_Create"
+	"FCU_Tools/Metric"
 )
 
-// M5_main 은 M5 지표의 총 진입점이다: 출력 디렉터리를 준비하고,  
-// M5 LDI 파일을 생성한 후 주 LDI에 병합한다.  
+func init() {
+	Metric.Register(m5{})
+}
+
+// m5 는 M5(ASIL 분리) 지표이며, init 에서 Metric 레지스트리에 등록된다.
+type m5 struct{}
+
+func (m5) Name() string { return "M5" }
+
+func (m5) Inputs() []*Metric.Input { return []*Metric.Input{Metric.ComponentInfoCsv} }
 
-func M5_main() {
+func (m5) Enabled(cfg *Config.Config) bool { return cfg.Metrics.M5.Enabled }
+
+func (m5) Compute(ctx *Metric.Context, model *Dependency_Graph.Graph) (*LDI.Document, error) {
+	return M5_compute(ctx.Config)
+}
+
+// M5_compute 는 M5 지표 계산 단계이다: 출력 디렉터리를 준비하고,
+// M5 지표 LDI 문서를 돌려준다. 파일 저장과 주 LDI 병합은 Stage_Graph가 따로 수행한다.
+func M5_compute(cfg *Config.Config) (*LDI.Document, error) {
 
 	//   1) File_Utils_M5.PrepareM5OutputDir를 호출하여 출력 디렉토리를 초기화한다.  
-	if err := File_Utils_M5.PrepareM5OutputDir(); err != nil {
-		fmt.Println("5 출력 디렉토리 준비 실패: ", err)
-		return
+	if err := File_Utils_M5.PrepareM5OutputDir(cfg); err != nil {
+		return nil, fmt.Errorf("5 출력 디렉토리 준비 실패: %v", err)
 	}
 
-	//   2) File_Utils_M5.GenerateM5LDIXml을 호출하여 component_info.csv을 읽고 M5.ldi.xml을 생성한다.  
-	File_Utils_M5.GenerateM5LDIXml()
-
-	//   3) LDI_M5_Create.MergeM5ToMainLDI를 호출하여 m5 및 m5demo 지표를 주 LDI 파일에 병합한다.  
-	LDI_M5_Create.MergeM5ToMainLDI()
+	//   2) File_Utils_M5.GenerateM5LDIXml을 호출하여 component_info.csv을 읽고 M5 LDI 문서를 만든다.  
+	return File_Utils_M5.GenerateM5LDIXml(cfg)
 }
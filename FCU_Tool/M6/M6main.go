@@ -2,24 +2,40 @@ package M6main
 
 import (
 	"fmt"
+	"FCU_Tools/Config"
+	"FCU_Tools/Dependency_Graph"
+	"FCU_Tools/LDI"
 	"FCU_Tools/M6/File_Utils_M6"
-	"FCU_Tools/M6/LDI_M6_Create"
+	"FCU_Tools/Metric"
 )
-// M6_main 은 M6 프로세스의 총 진입점이다: 출력 디렉터리를 준비하고,  
-// M6 LDI 파일을 생성한 후 주 LDI에 병합한다.  
 
-func M6_main() {
+func init() {
+	Metric.Register(m6{})
+}
+
+// m6 는 M6(ASIL 등급 의존 위반) 지표이며, init 에서 Metric 레지스트리에 등록된다.
+type m6 struct{}
+
+func (m6) Name() string { return "M6" }
+
+func (m6) Inputs() []*Metric.Input { return []*Metric.Input{Metric.AswCsv, Metric.ComponentInfoCsv} }
+
+func (m6) Enabled(cfg *Config.Config) bool { return cfg.Metrics.M6.Enabled }
+
+func (m6) Compute(ctx *Metric.Context, model *Dependency_Graph.Graph) (*LDI.Document, error) {
+	return M6_compute(ctx.Config, model)
+}
+
+// M6_compute 는 M6 지표 계산 단계이다: 출력 디렉터리를 준비하고,
+// M6 지표 LDI 문서를 돌려준다. 파일 저장과 주 LDI 병합은 Stage_Graph가 따로 수행한다.
+func M6_compute(cfg *Config.Config, g *Dependency_Graph.Graph) (*LDI.Document, error) {
 	//   1) File_Utils_M6.PrepareM2OutputDir를 호출하여 출력 디렉터리를 초기화한다.  
-	if err := File_Utils_M6.PrepareM2OutputDir(); err != nil {
-		fmt.Println("6 출력 디렉토리 준비 실패: ", err)
-		return
+	if err := File_Utils_M6.PrepareM2OutputDir(cfg); err != nil {
+		return nil, fmt.Errorf("6 출력 디렉토리 준비 실패: %v", err)
 	}
 
-	//   2) File_Utils_M6.GenerateM6LDIXml을 호출하여 M6 지표를 계산하고 M6.ldi.xml 및 M6.txt를 생성한다.  
-	File_Utils_M6.GenerateM6LDIXml()
-	
-	//   3) LDI_M6_Create.MergeM6ToMainLDI를 호출하여 M6 지표를 주 LDI 파일 result.ldi.xml에 병합한다.  
-	LDI_M6_Create.MergeM6ToMainLDI()
+	//   2) File_Utils_M6.GenerateM6LDIXml을 호출하여 M6 지표를 계산하고 M6 LDI 문서와 M6.txt를 만든다.  
+	return File_Utils_M6.GenerateM6LDIXml(cfg, g)
 
 
-}
\ No newline at end of file
+}
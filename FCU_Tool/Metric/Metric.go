@@ -0,0 +1,183 @@
+package Metric
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"FCU_Tools/Config"
+	"FCU_Tools/Dependency_Graph"
+	"FCU_Tools/LDI"
+	"FCU_Tools/Public_data"
+	"FCU_Tools/Run_Manifest"
+)
+
+// Metric 은 아키텍처 지표 하나이다.
+//
+// 새 지표는 패키지 하나로 추가한다:
+//  1. Metric 을 구현하고 init 에서 Register 로 등록한다.
+//  2. main.go 에 그 패키지를 빈 import(_ "FCU_Tools/Mx")로 추가한다.
+//
+// 실행 계획, 입력 확인, 결과 파일 쓰기, 주 LDI 병합, 명령행 하위 명령(mx)은 Stage_Graph 와 Command_Line 이
+// 레지스트리를 보고 처리하므로 손댈 필요가 없다.
+type Metric interface {
+	// Name 은 지표 이름이다 (예: "M3"). 명령행 이름(m3), --metrics 목록, 출력 폴더(<WorkDir>/M3/output)에 쓴다.
+	Name() string
+	// Inputs 는 실행 전에 있어야 하는 입력이다.
+	Inputs() []*Input
+	// Compute 는 요소(컴포넌트)별 지표 속성을 계산한다. 주 LDI는 건드리지 않는다.
+	// model 은 asw.csv 의존 그래프이며, 계획에 asw.csv가 필요한 단계가 없으면 nil 이다.
+	// 돌려준 문서는 <WorkDir>/<Name>/output/<Name>.ldi.xml 로 저장된 뒤 주 LDI에 병합된다.
+	Compute(ctx *Context, model *Dependency_Graph.Graph) (*LDI.Document, error)
+}
+
+// Toggle 은 설정 파일에서 켜고 끌 수 있는 지표가 구현한다. 구현하지 않은 지표는 항상 켜져 있다.
+type Toggle interface {
+	Enabled(cfg *Config.Config) bool
+}
+
+// Context 는 Compute 에 전달되는 실행 정보이다.
+type Context struct {
+	Config    *Config.Config
+	OutputDir string                 // <WorkDir>/<Name>/output (txt 등 부가 산출물 폴더)
+	Manifest  *Run_Manifest.Manifest // 실행 기록. 지표가 실제로 읽은 파일을 AddInput 으로 남긴다 (nil 가능)
+}
+
+var registry = map[string]Metric{}
+
+// Register 는 지표를 레지스트리에 등록한다. 지표 패키지의 init 에서 호출한다.
+// 이름이 비었거나 이미 등록된 이름이면 프로그램 오류이므로 panic 한다.
+func Register(m Metric) {
+	name := m.Name()
+	if name == "" {
+		panic("Metric.Register: 지표 이름이 비어 있습니다")
+	}
+	if Lookup(name) != nil {
+		panic(fmt.Sprintf("Metric.Register: 이미 등록된 지표입니다: %s", name))
+	}
+	registry[name] = m
+}
+
+// All 은 등록된 지표를 이름 순으로 돌려준다.
+func All() []Metric {
+	var list []Metric
+	for _, m := range registry {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list
+}
+
+// Lookup 은 이름(대소문자 무시)으로 지표를 찾는다. 없으면 nil.
+func Lookup(name string) Metric {
+	for key, m := range registry {
+		if strings.EqualFold(key, name) {
+			return m
+		}
+	}
+	return nil
+}
+
+// Enabled 는 설정에서 m 이 켜져 있는지 돌려준다.
+func Enabled(m Metric, cfg *Config.Config) bool {
+	if t, ok := m.(Toggle); ok {
+		return t.Enabled(cfg)
+	}
+	return true
+}
+
+// ======================== 입력 ========================
+
+// Input 은 단계가 필요로 하는 입력 파일(또는 폴더) 하나이다.
+// Path 가 빈 문자열을 돌려주면 아직 설정되지 않은 것으로 보고 Prompt 로 물어본다.
+// 빈 대답(또는 입력 끝)은 설정하지 않으므로 ResolveInputs 가 "설정되지 않음"으로 알린다 (현재 폴더로 추측하지 않는다).
+// Flag 는 이 입력을 지정하는 명령행 플래그 이름이다 (없으면 설정 파일로만 지정).
+type Input struct {
+	Name   string
+	IsDir  bool
+	Flag   string
+	Path   func(cfg *Config.Config) string
+	Prompt func(cfg *Config.Config) // 설정 파일과 플래그 어디에도 없을 때의 대화형 대체 입력
+	// Needed 가 false 를 돌려주면 이 설정에서는 쓰지 않는 입력이므로 묻지도 확인하지도 않는다 (nil 이면 항상 필요).
+	Needed func(cfg *Config.Config) bool
+	// Traced 가 true 이면 지표가 실제로 읽은 파일을 직접 run.json 에 기록하므로 Stage_Graph 는 이 입력을 기록하지 않는다.
+	Traced bool
+}
+
+// Required 는 cfg 에서 in 이 필요한지 돌려준다.
+func (in *Input) Required(cfg *Config.Config) bool {
+	return in.Needed == nil || in.Needed(cfg)
+}
+
+var (
+	AswCsv = &Input{
+		Name: "asw.csv",
+		Flag: "asw",
+		Path: func(cfg *Config.Config) string { return cfg.Inputs.AswCsv },
+		Prompt: func(cfg *Config.Config) {
+			if dir := Public_data.PromptLine("asw.csv를 저장할 폴더 경로를 입력하십시오: "); dir != "" {
+				cfg.Inputs.AswCsv = absPath(filepath.Join(dir, "asw.csv"))
+			}
+		},
+	}
+	// Models 는 model_list 만 쓸 때(model_include 없이)는 필요 없다.
+	// 모델 폴더 전체가 아니라 M1 이 실제로 분석한 모델 파일을 M1 이 run.json 에 기록한다.
+	Models = &Input{
+		Name:  "SLX 모델 폴더",
+		IsDir: true,
+		Flag:  "models",
+		Path:  func(cfg *Config.Config) string { return cfg.Inputs.ModelsDir },
+		Prompt: func(cfg *Config.Config) {
+			if dir := Public_data.PromptLine("请输入一个 Windows 路径： "); dir != "" {
+				cfg.Inputs.ModelsDir = absPath(dir)
+			}
+		},
+		Needed: func(cfg *Config.Config) bool {
+			return cfg.Inputs.ModelList == "" || len(cfg.Inputs.ModelInclude) > 0
+		},
+		Traced: true,
+	}
+	// ModelList 는 설정 파일의 inputs.model_list 로만 지정하며, 지정했을 때만 확인한다.
+	ModelList = &Input{
+		Name:   "모델 목록",
+		Path:   func(cfg *Config.Config) string { return cfg.Inputs.ModelList },
+		Needed: func(cfg *Config.Config) bool { return cfg.Inputs.ModelList != "" },
+	}
+	ComplexityJson = &Input{
+		Name:   "complexity.json",
+		Flag:   "m2-dir",
+		Path:   func(cfg *Config.Config) string { return cfg.Inputs.ComplexityJson },
+		Prompt: promptM2Dir,
+	}
+	RqVersusComponentCsv = &Input{
+		Name:   "rq_versus_component.csv",
+		Flag:   "m2-dir",
+		Path:   func(cfg *Config.Config) string { return cfg.Inputs.RqVersusComponentCsv },
+		Prompt: promptM2Dir,
+	}
+	ComponentInfoCsv = &Input{
+		Name: "component_info.csv",
+		Flag: "m3-dir",
+		Path: func(cfg *Config.Config) string { return cfg.Inputs.ComponentInfoCsv },
+		Prompt: func(cfg *Config.Config) {
+			if dir := Public_data.PromptLine("필요한 M3 파일(component_info.csv)이 포함된 폴더 경로를 입력하십시오: "); dir != "" {
+				cfg.SetM3InputDir(absPath(dir))
+			}
+		},
+	}
+)
+
+func promptM2Dir(cfg *Config.Config) {
+	if dir := Public_data.PromptLine("필요한 M2 파일(complexity.json 및 rq_versus_component.csv)이 포함된 폴더 경로를 입력하십시오: "); dir != "" {
+		cfg.SetM2InputDir(absPath(dir))
+	}
+}
+
+// absPath 는 대화형으로 입력받은 경로를 절대 경로로 만든다.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -1,78 +1,81 @@
 package Public_data
 
 import (
+	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 )
-var HierarchyTable [][]string
 
-// ConnectorFilePath는 사용자가 입력한 asw.csv 파일 경로를 저장합니다.
-var ConnectorFilePath string
+// 입력/출력 경로는 더 이상 이 패키지의 전역 변수에 두지 않는다.
+// 모든 경로는 Config.Config 에 담겨 각 단계에 명시적으로 전달된다.
 
-// OutputDir는 프로그램이 자동으로 만드는 출력 디렉토리 경로입니다.
-var OutputDir string
-
-// ComplexityJsonPath는 complexity.json의 경로입니다.
-var M2ComplexityJsonPath string
-
-// RqExcelPath는 rq_versus_component.xlsx의 경로입니다.
-var M2RqExcelPath string
-
-var M3component_infoxlsxPath string
-
-var M2OutputlPath string
-var M3OutputlPath string
-var M4OutputlPath string
-var M5OutputlPath string
-var M6OutputlPath string
-
-
-
-// SetConnectorFilePath 사용자가 입력한 connector.xlsx 파일 경로 설정
-func SetConnectorFilePath(path string) {
-	ConnectorFilePath = path
-}
-
-// SetM2InputDir는 complexity.json 및 rq_versus_component.xlsx가 포함된 사용자 입력의 디렉토리 경로를 설정합니다.
-func SetM2InputDir(dir string) error {
-	complexity := filepath.Join(dir, "complexity.json")
-	rqExcel := filepath.Join(dir, "rq_versus_component.xlsx")
-
-	if _, err := os.Stat(complexity); os.IsNotExist(err) {
-		return fmt.Errorf("complexity.json을 찾을 수 없습니다: %s", complexity)
+// InitOutputDirectory 는 출력 디렉토리를 초기화하고 존재하는 경우 재구성 비우기
+func InitOutputDirectory(outputPath string) error {
+	// Output 디렉토리가 이미 있으면 삭제합니다.
+	if _, err := os.Stat(outputPath); err == nil {
+		if err := os.RemoveAll(outputPath); err != nil {
+			return fmt.Errorf("이전 Output 디렉토리를 삭제하지 못했습니다.: %v", err)
+		}
 	}
-	if _, err := os.Stat(rqExcel); os.IsNotExist(err) {
-		return fmt.Errorf("rq_versus_component.xlsx 을 찾을 수 없습니다.: %s", rqExcel)
+
+	// 새 Output 디렉토리 만들기
+	if err := os.MkdirAll(outputPath, 0755); err != nil {
+		return fmt.Errorf("새 Output 디렉토리를 생성하지 못했습니다.: %v", err)
 	}
 
-	M2ComplexityJsonPath = complexity
-	M2RqExcelPath = rqExcel
 	return nil
 }
 
-// InitOutputDirectory 는 출력 디렉토리 Output 을 초기화하고 존재하는 경우 재구성 비우기
-func InitOutputDirectory() error {
-	// 현재 작업 디렉토리 가져오기 (프로젝트 루트)
-	baseDir, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("현재 작업 디렉토리를 가져오지 못했습니다.: %v", err)
+// UseOutputDirectory 는 이미 존재하는 출력 디렉토리를 삭제하지 않고 그대로 사용한다.
+// 개별 지표만 실행할 때 기존 result.ldi.xml에 병합하기 위해 사용한다.
+func UseOutputDirectory(outputPath string) error {
+	mainLDIPath := filepath.Join(outputPath, "result.ldi.xml")
+	if _, err := os.Stat(mainLDIPath); err != nil {
+		return fmt.Errorf("주 LDI 파일을 찾을 수 없습니다. 먼저 deps 또는 run을 실행하십시오: %s", mainLDIPath)
 	}
+	return nil
+}
 
-	outputPath := filepath.Join(baseDir, "Output")
-	OutputDir = outputPath
-
-	// Output 디렉토리가 이미 있으면 삭제합니다.
+// PrepareStageOutputDir 는 지표 출력 디렉토리를 비우고 다시 만든다.
+func PrepareStageOutputDir(outputPath string) error {
 	if _, err := os.Stat(outputPath); err == nil {
 		if err := os.RemoveAll(outputPath); err != nil {
-			return fmt.Errorf("이전 Output 디렉토리를 삭제하지 못했습니다.: %v", err)
+			return fmt.Errorf("이전 output 디렉토리를 삭제하지 못했습니다: %v", err)
 		}
 	}
 
-	// 새 Output 디렉토리 만들기
-	if err := os.Mkdir(outputPath, 0755); err != nil {
-		return fmt.Errorf("새 Output 디렉토리를 생성하지 못했습니다.: %v", err)
+	if err := os.MkdirAll(outputPath, 0755); err != nil {
+		return fmt.Errorf("output 디렉터리를 만드는 데 실패했습니다: %v", err)
 	}
-
 	return nil
 }
+
+// stdinReader 는 모든 대화형 입력이 공유하는 표준 입력 버퍼이다.
+// 단계마다 bufio.Reader를 새로 만들면 파이프 입력의 나머지 줄이 앞 단계 버퍼에 남아 사라진다.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// PromptLine 은 안내 문구를 출력하고 표준 입력에서 한 줄을 읽어 앞뒤 공백을 제거해 반환한다.
+// 명령행 플래그나 설정 파일로 값이 주어지지 않았을 때만 사용하는 대화형 대체 입력이다.
+func PromptLine(prompt string) string {
+	fmt.Print(prompt)
+	line, _ := stdinReader.ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+// warningCount 는 Warnf 로 출력한 경고 수이다 (run.json 의 경고 집계에 쓴다).
+var warningCount int64
+
+// Warnf 는 "⚠️ " 로 시작하는 경고 한 줄을 출력하고 경고 수를 하나 늘린다.
+// 분석을 멈추지 않는 문제(건너뛴 연결, 누락된 메타 정보 등)는 모두 이 함수로 알린다.
+func Warnf(format string, args ...interface{}) {
+	atomic.AddInt64(&warningCount, 1)
+	fmt.Printf("⚠️ "+format+"\n", args...)
+}
+
+// WarningCount 는 프로그램 시작 후 Warnf 로 출력한 경고 수를 돌려준다.
+func WarningCount() int {
+	return int(atomic.LoadInt64(&warningCount))
+}
@@ -0,0 +1,282 @@
+package Run_Manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"FCU_Tools/Config"
+	"FCU_Tools/Public_data"
+)
+
+// Version 은 도구 버전이다. 릴리스 빌드에서 지정한다:
+//
+//	go build -ldflags "-X FCU_Tools/Run_Manifest.Version=1.2.0"
+var Version = "dev"
+
+// FileName 은 주 LDI 옆에 쓰는 실행 기록 파일 이름이다.
+const FileName = "run.json"
+
+// 단계 상태
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Manifest 는 분석 실행 한 번의 기록(run.json)이다.
+// result.ldi.xml의 지표 값이 어떤 입력 파일(SHA-256)과 설정으로 만들어졌는지 추적하는 데 쓴다.
+type Manifest struct {
+	Tool       Tool           `json:"tool"`
+	Command    string         `json:"command"`
+	Status     string         `json:"status"`
+	Error      string         `json:"error,omitempty"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	DurationMs int64          `json:"duration_ms"`
+	Inputs     []InputFile    `json:"inputs"`
+	Config     Config.Config  `json:"config"`
+	Stages     []*StageRecord `json:"stages"`
+	Warnings   int            `json:"warnings"`
+	// Variants 는 M1 이 Variant Subsystem 마다 계산에 넣은 choice 이다 (metrics.m1.variants).
+	Variants []VariantChoice `json:"variants,omitempty"`
+	// Previous 는 기존 result.ldi.xml에 병합만 한 실행(m1~m6, merge)일 때 그 전 실행의 run.json 이다.
+	// 주 LDI에 남아 있는 다른 지표 값의 출처를 잃지 않도록 이어 붙인다.
+	Previous *Manifest `json:"previous,omitempty"`
+
+	warningsAtStart int
+	variantsAtStart int
+}
+
+// VariantChoice 는 Variant Subsystem 하나에서 M1 계산에 넣은 choice 이다.
+type VariantChoice struct {
+	Model   string   `json:"model"`
+	Block   string   `json:"block"` // Simulink 경로 (모델/서브시스템/...)
+	Mode    string   `json:"mode"`
+	Choices []string `json:"choices"`
+	Chosen  []string `json:"chosen"`
+}
+
+var (
+	variantsMu sync.Mutex
+	variants   []VariantChoice
+)
+
+// RecordVariants 는 M1 이 고른 variant 를 기록한다. 실행이 끝나면(Finish) run.json 에 들어간다.
+func RecordVariants(v ...VariantChoice) {
+	variantsMu.Lock()
+	defer variantsMu.Unlock()
+	variants = append(variants, v...)
+}
+
+// Tool 은 실행한 도구의 버전 정보이다.
+type Tool struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"` // 빌드한 git 커밋 (go build 가 기록한 경우)
+	Modified  bool   `json:"modified,omitempty"` // 커밋되지 않은 변경이 있는 트리에서 빌드했는지
+	GoVersion string `json:"go_version"`
+}
+
+// InputFile 은 실행에 쓰인 입력 파일 하나이다. 폴더 입력(SLX 모델 폴더)은 안의 파일마다 하나씩 기록한다.
+type InputFile struct {
+	Name   string `json:"name"` // 입력 이름 (예: "asw.csv", "SLX 모델 폴더")
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// StageRecord 는 단계 하나의 실행 기록이다.
+type StageRecord struct {
+	Name       string    `json:"name"`
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Warnings   int       `json:"warnings"`
+	Error      string    `json:"error,omitempty"`
+
+	warningsAtStart int
+}
+
+// Start 는 실행 기록을 시작한다. cfg 는 시작 시점의 값으로 복사해 둔다.
+func Start(command string, cfg *Config.Config) *Manifest {
+	variantsMu.Lock()
+	defer variantsMu.Unlock()
+	return &Manifest{
+		Tool:            toolInfo(),
+		Command:         command,
+		StartedAt:       time.Now(),
+		Config:          *cfg,
+		warningsAtStart: Public_data.WarningCount(),
+		variantsAtStart: len(variants),
+	}
+}
+
+func toolInfo() Tool {
+	t := Tool{Name: "FCU_Tools", Version: Version}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		t.GoVersion = info.GoVersion
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				t.Revision = s.Value
+			case "vcs.modified":
+				t.Modified = s.Value == "true"
+			}
+		}
+	}
+	return t
+}
+
+// LoadPrevious 는 outputDir 에 이미 있는 run.json 을 Previous 로 붙인다. 없거나 읽을 수 없으면 무시한다.
+func (m *Manifest) LoadPrevious(outputDir string) {
+	if m == nil {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(outputDir, FileName))
+	if err != nil {
+		return
+	}
+	var prev Manifest
+	if err := json.Unmarshal(data, &prev); err != nil {
+		Public_data.Warnf("기존 %s를 읽지 못해 이전 실행 기록을 잇지 않습니다: %v", FileName, err)
+		return
+	}
+	m.Previous = &prev
+}
+
+// AddInput 은 입력 파일(또는 폴더 안의 모든 파일)의 크기와 SHA-256을 기록한다.
+// 같은 경로는 한 번만 기록한다.
+func (m *Manifest) AddInput(name, path string) error {
+	if m == nil {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("입력 파일 확인 실패 [%s]: %v", path, err)
+	}
+	if !info.IsDir() {
+		return m.addFile(name, path)
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("입력 폴더 읽기 실패 [%s]: %v", path, err)
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		if err := m.addFile(name, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manifest) addFile(name, path string) error {
+	for _, in := range m.Inputs {
+		if in.Path == path {
+			return nil
+		}
+	}
+	sum, size, err := hashFile(path)
+	if err != nil {
+		return fmt.Errorf("입력 파일 해시 계산 실패 [%s]: %v", path, err)
+	}
+	m.Inputs = append(m.Inputs, InputFile{Name: name, Path: path, Size: size, SHA256: sum})
+	return nil
+}
+
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// BeginStage 는 단계 실행 기록을 시작한다.
+func (m *Manifest) BeginStage(name string) *StageRecord {
+	st := &StageRecord{Name: name, StartedAt: time.Now(), warningsAtStart: Public_data.WarningCount()}
+	if m != nil {
+		m.Stages = append(m.Stages, st)
+	}
+	return st
+}
+
+// End 는 단계 실행 기록을 마친다. err 가 nil 이 아니면 실패로 기록한다.
+func (st *StageRecord) End(err error) {
+	st.DurationMs = time.Since(st.StartedAt).Milliseconds()
+	st.Warnings = Public_data.WarningCount() - st.warningsAtStart
+	st.Status = StatusSuccess
+	if err != nil {
+		st.Status = StatusFailed
+		st.Error = err.Error()
+	}
+}
+
+// SkipStage 는 실행하지 않은 단계를 이유와 함께 기록한다.
+func (m *Manifest) SkipStage(name, reason string) {
+	if m == nil {
+		return
+	}
+	m.Stages = append(m.Stages, &StageRecord{Name: name, Status: StatusSkipped, StartedAt: time.Now(), Error: reason})
+}
+
+// Finish 는 실행 기록을 마친다. err 가 nil 이 아니면 실패로 기록한다.
+func (m *Manifest) Finish(err error) {
+	if m == nil {
+		return
+	}
+	m.FinishedAt = time.Now()
+	m.DurationMs = m.FinishedAt.Sub(m.StartedAt).Milliseconds()
+	m.Warnings = Public_data.WarningCount() - m.warningsAtStart
+	variantsMu.Lock()
+	m.Variants = append([]VariantChoice(nil), variants[m.variantsAtStart:]...)
+	variantsMu.Unlock()
+	m.Status = StatusSuccess
+	if err != nil {
+		m.Status = StatusFailed
+		m.Error = err.Error()
+	}
+}
+
+// Write 는 outputDir/run.json 을 쓰고 그 경로를 돌려준다.
+func (m *Manifest) Write(outputDir string) (string, error) {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return "", fmt.Errorf("출력 디렉토리 생성 실패: %v", err)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("%s 직렬화 실패: %v", FileName, err)
+	}
+	path := filepath.Join(outputDir, FileName)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("%s 쓰기 실패 [%s]: %v", FileName, path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,422 @@
+package Stage_Graph
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"FCU_Tools/Config"
+	"FCU_Tools/Dependency_Graph"
+	"FCU_Tools/LDI"
+	"FCU_Tools/LDI_Merge"
+	"FCU_Tools/Metric"
+	"FCU_Tools/Public_data"
+	"FCU_Tools/Run_Manifest"
+	"FCU_Tools/SWC_Dependence"
+)
+
+// Stage 는 파이프라인의 한 단계이다.
+//   - Needs   : 실행 전에 있어야 하는 입력
+//   - After   : 먼저 끝나 있어야 하는 단계 (지표는 모두 주 LDI를 만드는 deps 뒤에 온다)
+//   - Compute : 지표를 계산하여 <WorkDir>/<Mx>/output 에만 쓴다 (주 LDI는 건드리지 않는다).
+//     g 는 실행마다 한 번 만든 asw.csv 의존 그래프이며, asw.csv가 필요 없는 계획에서는 nil 이다.
+//     rec 는 실행 기록이며, 지표가 실제로 읽은 입력 파일을 남기는 데 쓴다.
+//   - Partial : 계산 결과를 주 LDI에 병합할 부분 LDI로 읽는다 (deps 처럼 병합이 없는 단계는 nil)
+//   - Output  : 병합에 쓰이는 계산 결과 경로 (merge 명령에서 존재 여부 확인용)
+type Stage struct {
+	Name    string
+	Needs   []*Metric.Input
+	After   []string
+	Compute func(cfg *Config.Config, g *Dependency_Graph.Graph, rec *Run_Manifest.Manifest) error
+	Partial func(cfg *Config.Config) (*LDI.Document, error)
+	Output  func(cfg *Config.Config) string
+}
+
+// Deps 는 주 LDI(result.ldi.xml)를 만드는 SWC 의존관계 단계의 이름이다.
+const Deps = "deps"
+
+var (
+	stagesOnce sync.Once
+	stageList  []*Stage
+)
+
+// allStages 는 deps 단계와 Metric 레지스트리에 등록된 지표 단계를 선언 순서(deps, 지표 이름 순)로 돌려준다.
+// 지표 패키지의 init 이 모두 끝난 뒤(main 실행 이후)에 처음 호출되므로 레지스트리가 완성되어 있다.
+func allStages() []*Stage {
+	stagesOnce.Do(func() {
+		stageList = []*Stage{{Name: Deps, Needs: []*Metric.Input{Metric.AswCsv}, Compute: computeDeps}}
+		for _, m := range Metric.All() {
+			stageList = append(stageList, metricStage(m))
+		}
+	})
+	return stageList
+}
+
+// metricStage 는 지표 하나를 단계로 감싼다.
+// Compute 는 지표가 돌려준 LDI 문서를 <WorkDir>/<Name>/output/<Name>.ldi.xml 로 저장하고,
+// Partial 은 그 파일을 다시 읽어 병합에 쓴다 (merge 명령은 저장된 파일만으로 병합할 수 있다).
+func metricStage(m Metric.Metric) *Stage {
+	name := m.Name()
+	output := stageOutput(name, name+".ldi.xml")
+	return &Stage{
+		Name:  name,
+		Needs: m.Inputs(),
+		After: []string{Deps},
+		Compute: func(cfg *Config.Config, g *Dependency_Graph.Graph, rec *Run_Manifest.Manifest) error {
+			ctx := &Metric.Context{Config: cfg, OutputDir: cfg.StageOutputDir(name), Manifest: rec}
+			doc, err := m.Compute(ctx, g)
+			if err != nil {
+				return err
+			}
+			path := output(cfg)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return fmt.Errorf("%s 출력 폴더 생성 실패: %v", name, err)
+			}
+			if err := doc.Write(path); err != nil {
+				return err
+			}
+			fmt.Printf("📄 %s 지표 계산 완료: %s\n", name, path)
+			return nil
+		},
+		Partial: func(cfg *Config.Config) (*LDI.Document, error) {
+			return LDI.Read(output(cfg))
+		},
+		Output: output,
+	}
+}
+
+func stageOutput(stage, name string) func(cfg *Config.Config) string {
+	return func(cfg *Config.Config) string {
+		return filepath.Join(cfg.StageOutputDir(stage), name)
+	}
+}
+
+// computeDeps 는 Output 폴더를 초기화하고 asw.csv로 SWC 의존관계 LDI를 만든다.
+func computeDeps(cfg *Config.Config, g *Dependency_Graph.Graph, _ *Run_Manifest.Manifest) error {
+	// 분석 결과는 Output폴더에 생성함. Output풀더를 초기화(이미 있으면 삭제, 없으면 생성)
+	if err := Public_data.InitOutputDirectory(cfg.OutputDir); err != nil {
+		return fmt.Errorf("출력 디렉토리 초기화 실패: %v", err)
+	}
+
+	// asw.csv 파일 내용에 따라 각 컴포넌트 간의 의존 관계를 분석합니다. 구체적으로 컴포넌트 간 의존 강도 분석(ldi.xml에서 <uses provider="CL1MGR" strength="1"/>의 strength 값)
+	if err := SWC_Dependence.AnalyzeSWCDependencies(cfg, g); err != nil {
+		return fmt.Errorf("의존관계 분석 실패: %v", err)
+	}
+	fmt.Println("의존관계 분석 완료.")
+	return nil
+}
+
+// Lookup 은 이름(대소문자 무시)으로 단계를 찾는다.
+func Lookup(name string) *Stage {
+	for _, st := range allStages() {
+		if strings.EqualFold(st.Name, name) {
+			return st
+		}
+	}
+	return nil
+}
+
+// MetricNames 는 등록된 지표 단계 이름을 선언 순서(이름 순)대로 돌려준다.
+func MetricNames() []string {
+	var names []string
+	for _, st := range allStages() {
+		if st.Partial != nil {
+			names = append(names, st.Name)
+		}
+	}
+	return names
+}
+
+// EnabledMetrics 는 설정에서 켜진 지표 이름을 돌려준다.
+func EnabledMetrics(cfg *Config.Config) []string {
+	var names []string
+	for _, m := range Metric.All() {
+		if Metric.Enabled(m, cfg) {
+			names = append(names, m.Name())
+		} else {
+			fmt.Printf("ℹ️ %s 지표는 설정에서 꺼져 있어 건너뜁니다.\n", m.Name())
+		}
+	}
+	return names
+}
+
+// ParseMetrics 는 "m3,m6" 형식의 목록을 단계 이름으로 바꾼다.
+func ParseMetrics(list string) ([]string, error) {
+	var names []string
+	seen := map[string]bool{}
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		st := Lookup(part)
+		if st == nil || st.Partial == nil {
+			return nil, fmt.Errorf("알 수 없는 지표입니다: %s (사용 가능: %s)", part, strings.Join(MetricNames(), ", "))
+		}
+		if !seen[st.Name] {
+			seen[st.Name] = true
+			names = append(names, st.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("실행할 지표가 없습니다: %q", list)
+	}
+	return names, nil
+}
+
+// Plan 은 선택한 단계와 그 선행 단계(After)를 모아 실행 순서대로 돌려준다.
+// done 에 있는 단계는 이미 끝난 것으로 보고 계획에 넣지 않는다 (예: 기존 result.ldi.xml을 쓰는 m1~m6 명령).
+// 순서는 선행 단계가 항상 먼저이고, 그 외에는 선언 순서(deps, 지표 이름 순)를 따른다.
+func Plan(names []string, done ...string) ([]*Stage, error) {
+	skip := map[string]bool{}
+	for _, d := range done {
+		skip[d] = true
+	}
+
+	selected := map[string]bool{}
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		st := Lookup(name)
+		if st == nil {
+			return fmt.Errorf("알 수 없는 단계입니다: %s", name)
+		}
+		for _, p := range path {
+			if p == st.Name {
+				return fmt.Errorf("단계 순환 의존: %s -> %s", strings.Join(path, " -> "), st.Name)
+			}
+		}
+		if selected[st.Name] || skip[st.Name] {
+			return nil
+		}
+		for _, dep := range st.After {
+			if err := visit(dep, append(path, st.Name)); err != nil {
+				return err
+			}
+		}
+		selected[st.Name] = true
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	// 선행 단계가 끝난 것부터 선언 순서대로 꺼낸다.
+	var plan []*Stage
+	placed := map[string]bool{}
+	for _, d := range done {
+		placed[d] = true
+	}
+	for len(plan) < len(selected) {
+		progressed := false
+		for _, st := range allStages() {
+			if !selected[st.Name] || placed[st.Name] {
+				continue
+			}
+			ready := true
+			for _, dep := range st.After {
+				if !placed[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				plan = append(plan, st)
+				placed[st.Name] = true
+				progressed = true
+			}
+		}
+		if !progressed {
+			return nil, fmt.Errorf("단계 실행 순서를 정할 수 없습니다")
+		}
+	}
+	return plan, nil
+}
+
+// ResolveInputs 는 계획된 단계가 필요로 하는 입력을 실행 전에 모두 확인한다 (이 설정에서 쓰지 않는 입력은 제외).
+// 설정되지 않은 입력은 대화형으로 물어보고, 그래도 없거나 존재하지 않는 입력은
+// 어떤 단계가 필요로 하는지와 함께 한 번에 모아 오류로 돌려준다.
+func ResolveInputs(cfg *Config.Config, plan []*Stage) error {
+	var order []*Metric.Input
+	users := map[*Metric.Input][]string{}
+	for _, st := range plan {
+		for _, in := range st.Needs {
+			if !in.Required(cfg) {
+				continue
+			}
+			if _, ok := users[in]; !ok {
+				order = append(order, in)
+			}
+			users[in] = append(users[in], st.Name)
+		}
+	}
+
+	// 같은 플래그의 입력(m2-dir 의 두 파일)은 한 번만 물어본다.
+	asked := map[string]bool{}
+	for _, in := range order {
+		if in.Path(cfg) == "" && in.Prompt != nil && (in.Flag == "" || !asked[in.Flag]) {
+			asked[in.Flag] = true
+			in.Prompt(cfg)
+		}
+	}
+
+	var missing []string
+	for _, in := range order {
+		path := in.Path(cfg)
+		problem := ""
+		if path == "" {
+			problem = "설정되지 않음"
+		} else if info, err := os.Stat(path); err != nil {
+			problem = fmt.Sprintf("찾을 수 없음 [%s]", path)
+		} else if info.IsDir() != in.IsDir {
+			if in.IsDir {
+				problem = fmt.Sprintf("폴더가 아님 [%s]", path)
+			} else {
+				problem = fmt.Sprintf("파일이 아님 [%s]", path)
+			}
+		}
+		if problem != "" {
+			missing = append(missing, fmt.Sprintf("  - %s: %s (필요한 단계: %s)", in.Name, problem, strings.Join(users[in], ", ")))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("필요한 입력이 없습니다:\n%s", strings.Join(missing, "\n"))
+	}
+	return nil
+}
+
+// Execute 는 계획된 단계를 두 단계로 실행한다.
+//  0. 계획에 asw.csv가 필요한 단계가 있으면 asw.csv를 한 번만 읽어 의존 그래프를 만든다.
+//  1. 계산: 모든 단계의 Compute 를 순서대로 실행한다. 한 지표가 실패해도 나머지 지표는 계속 계산하지만,
+//     선행 단계(deps)가 실패하면 그 뒤의 단계는 실행하지 않는다.
+//  2. 병합: 모든 계산이 성공했을 때만 결과를 주 LDI에 병합한다 (MergeOutputs 참조).
+//
+// 계산이 하나라도 실패하면 주 LDI에는 아무 지표도 병합하지 않으므로 일부만 병합된 result.ldi.xml이 남지 않는다.
+//
+// rec 에는 입력 파일(SHA-256)과 단계별 소요 시간, 상태, 경고 수를 기록한다 (run.json).
+// Traced 입력은 여기서 기록하지 않고 지표가 실제로 읽은 파일을 기록한다.
+func Execute(cfg *Config.Config, plan []*Stage, rec *Run_Manifest.Manifest) error {
+	for _, st := range plan {
+		for _, in := range st.Needs {
+			if !in.Required(cfg) || in.Traced {
+				continue
+			}
+			if err := rec.AddInput(in.Name, in.Path(cfg)); err != nil {
+				return err
+			}
+		}
+	}
+
+	var g *Dependency_Graph.Graph
+	if needsInput(plan, Metric.AswCsv) {
+		gs := rec.BeginStage("graph")
+		var err error
+		g, err = Dependency_Graph.Load(cfg)
+		gs.End(err)
+		if err != nil {
+			return fmt.Errorf("asw.csv 의존 그래프 생성 실패: %v", err)
+		}
+	}
+
+	failed := map[string]bool{}
+	var failedNames []string
+	for _, st := range plan {
+		blocked := ""
+		for _, dep := range st.After {
+			if failed[dep] {
+				blocked = dep
+				break
+			}
+		}
+		if blocked != "" {
+			Public_data.Warnf("%s 단계는 선행 단계 %s 실패로 건너뜁니다.", st.Name, blocked)
+			rec.SkipStage(st.Name, fmt.Sprintf("선행 단계 %s 실패", blocked))
+			failed[st.Name] = true
+			failedNames = append(failedNames, st.Name)
+			continue
+		}
+		sr := rec.BeginStage(st.Name)
+		err := st.Compute(cfg, g, rec)
+		sr.End(err)
+		if err != nil {
+			fmt.Printf("❌ %s 단계 실패: %v\n", st.Name, err)
+			failed[st.Name] = true
+			failedNames = append(failedNames, st.Name)
+		}
+	}
+	if len(failedNames) > 0 {
+		return fmt.Errorf("실패한 단계: %s (주 LDI에는 지표를 병합하지 않았습니다)", strings.Join(failedNames, ", "))
+	}
+
+	return MergeOutputs(cfg, plan, rec)
+}
+
+func needsInput(plan []*Stage, in *Metric.Input) bool {
+	for _, st := range plan {
+		for _, need := range st.Needs {
+			if need == in {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MergeOutputs 는 단계들의 계산 결과(부분 LDI)를 모두 읽은 뒤 LDI_Merge 로 주 LDI에 한 번에 병합한다.
+// 부분 LDI를 하나라도 읽지 못하거나 충돌을 해결하지 못하면 주 LDI는 변경하지 않는다.
+// 충돌 정책과 새 요소 추가 여부는 cfg.Merge 를 따른다. 병합한 결과 파일은 rec 에 입력으로 기록한다.
+func MergeOutputs(cfg *Config.Config, plan []*Stage, rec *Run_Manifest.Manifest) error {
+	if !hasPartial(plan) {
+		return nil
+	}
+	ms := rec.BeginStage("merge")
+	err := mergeOutputs(cfg, plan, rec)
+	ms.End(err)
+	return err
+}
+
+func mergeOutputs(cfg *Config.Config, plan []*Stage, rec *Run_Manifest.Manifest) error {
+	var sources []LDI_Merge.Source
+	for _, st := range plan {
+		if st.Partial == nil {
+			continue
+		}
+		doc, err := st.Partial(cfg)
+		if err != nil {
+			return fmt.Errorf("%s 결과 읽기 실패 (주 LDI는 변경되지 않았습니다): %v", st.Name, err)
+		}
+		if err := rec.AddInput(st.Name+" 결과", st.Output(cfg)); err != nil {
+			return err
+		}
+		sources = append(sources, LDI_Merge.Source{
+			Name:         st.Name,
+			Doc:          doc,
+			AddUnmatched: containsFold(cfg.Merge.AddUnmatched, st.Name),
+		})
+	}
+	if _, err := LDI_Merge.MergeFile(cfg.MainLDIPath(), sources, cfg.Merge); err != nil {
+		return fmt.Errorf("주 LDI 병합 실패: %v", err)
+	}
+	return nil
+}
+
+func hasPartial(plan []*Stage) bool {
+	for _, st := range plan {
+		if st.Partial != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, name string) bool {
+	for _, s := range list {
+		if strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return false
+}